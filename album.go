@@ -0,0 +1,83 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrAlbumNotFound is returned by album stores when the requested album does not exist.
+	ErrAlbumNotFound = errors.New("album not found")
+	// ErrAlbumConflict is returned when the group already has an album with the same title.
+	ErrAlbumConflict = errors.New("album already exists")
+	// ErrTrackNotFound is returned when removing a song that is not on the album.
+	ErrTrackNotFound = errors.New("song is not on the album")
+	// ErrTrackConflict is returned when adding a song that is already on the album.
+	ErrTrackConflict = errors.New("song is already on the album")
+)
+
+type Album struct {
+	Id          int         `json:"id"`
+	Title       string      `json:"title"`
+	GroupId     int         `json:"groupId"`
+	Group       string      `json:"group"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2011-08-11"`
+	CoverLink   string      `json:"coverLink"`
+	TrackCount  int         `json:"trackCount"`
+	// Tracks are ordered by disc and number, they are only filled in for a single album.
+	Tracks []Track `json:"tracks,omitzero"`
+}
+
+type Track struct {
+	Disc   int  `json:"disc"`
+	Number int  `json:"number"`
+	Song   Song `json:"song"`
+}
+
+// TrackPosition places a song on an album, zero disc means the first disc and
+// zero number the end of the disc.
+type TrackPosition struct {
+	SongId int `json:"songId"`
+	Disc   int `json:"disc,omitempty"`
+	Number int `json:"number,omitempty"`
+}
+
+type AlbumPost struct {
+	Title       *string      `json:"title"`
+	Group       *string      `json:"group"`
+	ReleaseDate *ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2011-08-11"`
+	CoverLink   *string      `json:"coverLink"`
+}
+
+// AlbumListOptions selects and pages the albums returned by AlbumStore.List.
+type AlbumListOptions struct {
+	// GroupId limits the list to the albums of one group.
+	GroupId int
+	Limit   int
+	Offset  int
+}
+
+// AlbumStore keeps albums and their track lists. Track numbers on a disc count from 1
+// without gaps, adding or removing a track renumbers the following ones.
+type AlbumStore interface {
+	// List returns a page of albums without tracks, ordered by group, release date and title.
+	List(ctx context.Context, opts AlbumListOptions) ([]Album, error)
+	// Count returns the number of albums, of one group when groupId is not zero.
+	Count(ctx context.Context, groupId int) (int, error)
+	// Get returns the album with its tracks or ErrAlbumNotFound.
+	Get(ctx context.Context, id int) (Album, error)
+	// Create stores a new album of the group named album.Group, creating the group when
+	// missing. It returns the album with its ids set, or ErrAlbumConflict.
+	Create(ctx context.Context, album Album) (Album, error)
+	// Delete removes the album and its track list or returns ErrAlbumNotFound.
+	Delete(ctx context.Context, id int) error
+	// AddTrack puts a song on the album at the given position, shifting the tracks at
+	// and after it. It returns ErrAlbumNotFound, ErrNotFound or ErrTrackConflict.
+	AddTrack(ctx context.Context, albumId int, track TrackPosition) error
+	// SetTracks replaces the track list, tracks are numbered per disc in the given
+	// order and their Number is ignored. It returns ErrAlbumNotFound, ErrNotFound or
+	// ErrTrackConflict when a song is listed twice.
+	SetTracks(ctx context.Context, albumId int, tracks []TrackPosition) error
+	// RemoveTrack takes a song off the album or returns ErrAlbumNotFound or ErrTrackNotFound.
+	RemoveTrack(ctx context.Context, albumId, songId int) error
+}
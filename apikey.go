@@ -0,0 +1,90 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned by API key stores when the requested key does not exist.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrQuotaExceeded is returned when an API key has used up its daily quota.
+	ErrQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+// Scope limits what an API key may do, see auth.ScopeAllows for the permissions of each scope.
+type Scope string
+
+const (
+	ScopeReadSongs  Scope = "read:songs"
+	ScopeWriteSongs Scope = "write:songs"
+)
+
+// Valid reports whether s is one of the known scopes.
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeReadSongs, ScopeWriteSongs:
+		return true
+	}
+	return false
+}
+
+// APIKey is a credential of a non-interactive client, sent in the X-API-Key header.
+// Only a hash of the key is stored, Prefix is kept to tell keys apart in listings.
+type APIKey struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" example:"mlk_3q2fXw"`
+	Scopes     []Scope    `json:"scopes" enums:"read:songs,write:songs"`
+	CreatedBy  int        `json:"createdBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	// DailyQuota caps the requests per UTC day, zero means no cap. UsedToday counts
+	// the requests of the current day, it is only kept for keys with a quota.
+	DailyQuota int    `json:"dailyQuota"`
+	UsedToday  int    `json:"usedToday"`
+	KeyHash    []byte `json:"-"`
+}
+
+// APIKeySecret is returned when a key is minted or rotated, the only time Key is shown.
+type APIKeySecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyPost struct {
+	Name       *string `json:"name" example:"enrichment job"`
+	Scopes     []Scope `json:"scopes" enums:"read:songs,write:songs"`
+	DailyQuota int     `json:"dailyQuota" example:"10000"`
+}
+
+// APIKeyPatch renames a key and changes its quota when the fields are given,
+// a dailyQuota of 0 removes the quota.
+type APIKeyPatch struct {
+	Name       string `json:"name,omitempty"`
+	DailyQuota *int   `json:"dailyQuota,omitempty"`
+}
+
+// APIKeyStore keeps API keys.
+type APIKeyStore interface {
+	// List returns keys ordered by id.
+	List(ctx context.Context, limit, offset int) ([]APIKey, error)
+	// Count returns the number of keys.
+	Count(ctx context.Context) (int, error)
+	// Get returns the key with the given id or ErrAPIKeyNotFound.
+	Get(ctx context.Context, id int) (APIKey, error)
+	// Create stores a new key and returns it with its id set.
+	Create(ctx context.Context, key APIKey) (APIKey, error)
+	// Update changes name and daily quota of key.Id or returns ErrAPIKeyNotFound.
+	Update(ctx context.Context, key APIKey) error
+	// Rotate replaces the prefix and hash of a key and clears its last use, or returns ErrAPIKeyNotFound.
+	Rotate(ctx context.Context, id int, prefix string, keyHash []byte) (APIKey, error)
+	// Delete revokes the key, unknown ids are ignored.
+	Delete(ctx context.Context, id int) error
+	// Use returns the key with the given hash and records its use, or ErrTokenInvalid.
+	Use(ctx context.Context, keyHash []byte) (APIKey, error)
+	// UseQuota counts a request of the key against its daily quota and returns the
+	// requests of the day so far, or ErrQuotaExceeded once quota requests were made.
+	UseQuota(ctx context.Context, id, quota int) (int, error)
+}
@@ -0,0 +1,27 @@
+// Command fakeinfo serves a fake music-info API for local runs, point INFOURL at it.
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib/internal/musicinfo/musicinfotest"
+)
+
+func main() {
+	addr, set := os.LookupEnv("FAKEINFOADDR")
+	if !set {
+		addr = ":8002"
+	}
+
+	h := musicinfotest.NewHandler()
+	h.Add("Muse", "Supermassive Black Hole", musicinfotest.Song{
+		ReleaseDate: "16.07.2006",
+		Text:        `Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?\nYou caught me under false pretenses\nHow long before you let me go?\n\nOoh\nYou set my soul alight\nOoh\nYou set my soul alight`,
+		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
+	})
+
+	log.Info("Fake music info API is listening on " + addr)
+	log.Fatal(http.ListenAndServe(addr, h))
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/charmbracelet/log"
+	"github.com/golang-migrate/migrate/v4"
 	"github.com/joho/godotenv"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
 	"github.com/lynxbites/musiclib/internal/db"
+	"github.com/lynxbites/musiclib/internal/httpcache"
+	"github.com/lynxbites/musiclib/internal/jobs"
+	"github.com/lynxbites/musiclib/internal/musicinfo"
+	"github.com/lynxbites/musiclib/internal/ratelimit"
 	"github.com/lynxbites/musiclib/internal/routes"
 	_ "github.com/swaggo/http-swagger/example/go-chi/docs"
 	_ "github.com/swaggo/http-swagger/v2"
@@ -18,6 +28,14 @@ import (
 // @schemes http
 // @host localhost:8000
 // @BasePath /api/
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /v1/auth/login, sent as "Bearer <token>".
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key minted by an admin at /v1/api-keys.
 var runSwagger bool
 
 func init() {
@@ -45,13 +63,122 @@ func init() {
 func main() {
 	routerSwagger := routes.NewSwaggerRouter()
 
-	router := routes.NewRouter()
+	dbConfig, err := db.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := db.New(context.Background(), dbConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.Health(context.Background()); err != nil {
+		log.Warn("Database is not reachable yet: " + err.Error())
+	}
+
+	services := routes.Services{
+		Songs:          db.NewSongStore(conn),
+		Groups:         db.NewGroupStore(conn),
+		Albums:         db.NewAlbumStore(conn),
+		Playlists:      db.NewPlaylistStore(conn),
+		SmartPlaylists: db.NewSmartPlaylistStore(conn),
+	}
+	authConfig, err := auth.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case authConfig.Disabled:
+		log.Warn("AUTHDISABLED is set, the API accepts mutations without a token.")
+	case len(authConfig.Secret) == 0 && authConfig.PrivateKeyFile == "":
+		log.Fatal("JWTSECRET or JWTPRIVATEKEY must be set, set AUTHDISABLED=true to run without authentication.")
+	default:
+		authenticator, err := auth.New(authConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		services.Users = db.NewUserStore(conn)
+		services.Auth = authenticator
+		services.APIKeys = db.NewAPIKeyStore(conn)
+	}
+	infoConfig, err := musicinfo.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobsConfig, err := jobs.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var pool *jobs.Pool
+	if infoConfig.BaseURL == "" {
+		log.Warn("INFOURL is not set, posted songs will not be enriched.")
+	} else {
+		info, err := musicinfo.New(infoConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		services.Info = info
+		services.Jobs = db.NewJobQueue(conn, jobsConfig.MaxAttempts)
+		pool = jobs.NewPool(jobsConfig, services.Jobs)
+		pool.Handle(musiclib.JobKindEnrichSong, jobs.EnrichSong(services.Songs, info))
+	}
+
+	rateConfig, err := ratelimit.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	services.RateLimit = ratelimit.New(rateConfig, services.APIKeys)
+	if !services.RateLimit.Enabled() {
+		log.Warn("RATELIMIT is not set, clients are not throttled.")
+	}
+
+	services.CacheControl, err = httpcache.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flags := map[string]*bool{
+		"SONGPUTCREATES":     &services.PutCreates,
+		"SONGREQUIREIFMATCH": &services.RequireIfMatch,
+	}
+	for key, dst := range flags {
+		v, ok := os.LookupEnv(key)
+		if !ok || v == "" {
+			continue
+		}
+		*dst, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Fatal("parse " + key + ": invalid value " + strconv.Quote(v))
+		}
+	}
+
+	router := routes.NewRouter(services)
 
 	m, err := db.Migration()
 	if err != nil {
 		log.Fatal(err)
 	}
-	m.Up()
+	// A failed migration, e.g. release dates it cannot parse, must stop the server.
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Fatal("migrate: " + err.Error())
+	}
+
+	if services.Users != nil {
+		if authConfig.AdminUsername == "" {
+			log.Warn("ADMINUSERNAME is not set, registered users are viewers and nobody can manage them.")
+		} else {
+			admin, err := auth.EnsureAdmin(context.Background(), services.Users, authConfig.AdminUsername, authConfig.AdminPassword)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Info("Admin account is ready", "username", admin.Username, "userId", admin.Id)
+		}
+	}
+
+	if pool != nil {
+		go pool.Run(context.Background())
+		log.Info("Started job workers", "workers", jobsConfig.Workers)
+	}
 
 	go http.ListenAndServe(":8001", routerSwagger)
 	log.Info("Go to: http://localhost:8001/doc/index.html to open Swagger")
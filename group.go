@@ -0,0 +1,46 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrGroupNotFound is returned by group stores when the requested group does not exist.
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupConflict is returned by group stores when a group with the same name already exists.
+	ErrGroupConflict = errors.New("group already exists")
+	// ErrGroupNotEmpty is returned when deleting a group that still has songs or albums.
+	ErrGroupNotEmpty = errors.New("group has songs or albums")
+)
+
+type Group struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	SongCount int    `json:"songCount"`
+}
+
+type GroupPost struct {
+	Name *string `json:"name"`
+}
+
+type GroupPatch struct {
+	Name string `json:"name,omitempty"`
+}
+
+// GroupStore keeps the groups songs belong to. Group names are unique regardless of case.
+type GroupStore interface {
+	// List returns a page of groups ordered by name, zero limit means no limit.
+	List(ctx context.Context, limit, offset int) ([]Group, error)
+	// Count returns the number of groups.
+	Count(ctx context.Context) (int, error)
+	// Get returns the group with the given id or ErrGroupNotFound.
+	Get(ctx context.Context, id int) (Group, error)
+	// Create stores a new group and returns it with its id set, or ErrGroupConflict.
+	Create(ctx context.Context, name string) (Group, error)
+	// Update renames group.Id, which renames it for all of its songs. It returns
+	// ErrGroupNotFound or ErrGroupConflict.
+	Update(ctx context.Context, group Group) error
+	// Delete removes an empty group or returns ErrGroupNotFound or ErrGroupNotEmpty.
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// EnsureAdmin makes the user an admin, creating the account with the password when it
+// does not exist. An existing account keeps its password, so changing ADMINPASSWORD
+// later does not reset it.
+func EnsureAdmin(ctx context.Context, users musiclib.UserStore, username, password string) (musiclib.User, error) {
+	user, err := users.GetByName(ctx, username)
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return user, err
+		}
+		user, err = users.Create(ctx, musiclib.User{Username: username, Role: musiclib.RoleAdmin, PasswordHash: hash})
+		if err != nil {
+			return user, fmt.Errorf("create admin %q: %w", username, err)
+		}
+		return user, nil
+	}
+	if err != nil {
+		return user, err
+	}
+	if user.Role == musiclib.RoleAdmin {
+		return user, nil
+	}
+	user, err = users.SetRole(ctx, user.Id, musiclib.RoleAdmin)
+	if err != nil {
+		return user, fmt.Errorf("promote %q to admin: %w", username, err)
+	}
+	return user, nil
+}
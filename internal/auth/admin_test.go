@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// fakeUsers implements the parts of musiclib.UserStore EnsureAdmin uses.
+type fakeUsers struct {
+	musiclib.UserStore
+	users []musiclib.User
+}
+
+func (s *fakeUsers) GetByName(ctx context.Context, username string) (musiclib.User, error) {
+	for _, user := range s.users {
+		if strings.EqualFold(user.Username, username) {
+			return user, nil
+		}
+	}
+	return musiclib.User{}, musiclib.ErrUserNotFound
+}
+
+func (s *fakeUsers) Create(ctx context.Context, user musiclib.User) (musiclib.User, error) {
+	user.Id = len(s.users) + 1
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *fakeUsers) SetRole(ctx context.Context, id int, role musiclib.Role) (musiclib.User, error) {
+	s.users[id-1].Role = role
+	return s.users[id-1], nil
+}
+
+func TestEnsureAdminCreates(t *testing.T) {
+	users := &fakeUsers{}
+
+	admin, err := EnsureAdmin(context.Background(), users, "admin", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.Role != musiclib.RoleAdmin || len(users.users) != 1 {
+		t.Fatalf("admin = %+v, users = %+v, want one new admin", admin, users.users)
+	}
+	if !CheckPassword(admin.PasswordHash, "correct horse") {
+		t.Error("admin password does not match ADMINPASSWORD")
+	}
+
+	// Restarting finds the account instead of creating another one.
+	if _, err := EnsureAdmin(context.Background(), users, "Admin", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users.users) != 1 {
+		t.Errorf("users = %+v, want the admin only", users.users)
+	}
+}
+
+func TestEnsureAdminPromotes(t *testing.T) {
+	hash, err := HashPassword("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUsers{users: []musiclib.User{{Id: 1, Username: "ops", Role: musiclib.RoleViewer, PasswordHash: hash}}}
+
+	admin, err := EnsureAdmin(context.Background(), users, "ops", "new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.Role != musiclib.RoleAdmin {
+		t.Errorf("role = %q, want admin", admin.Role)
+	}
+	if !CheckPassword(users.users[0].PasswordHash, "old password") {
+		t.Error("the password of the existing account was changed")
+	}
+}
+
+func TestEnsureAdminPasswordTooLong(t *testing.T) {
+	_, err := EnsureAdmin(context.Background(), &fakeUsers{}, "admin", strings.Repeat("x", 73))
+	if !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("err = %v, want ErrPasswordTooLong", err)
+	}
+}
+
+func TestConfigFromEnvAdmin(t *testing.T) {
+	t.Setenv("ADMINUSERNAME", " admin ")
+	t.Setenv("ADMINPASSWORD", "")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("ADMINUSERNAME without ADMINPASSWORD was accepted")
+	}
+
+	t.Setenv("ADMINPASSWORD", "short")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("a 5 byte ADMINPASSWORD was accepted")
+	}
+
+	t.Setenv("ADMINPASSWORD", "correct horse")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AdminUsername != "admin" || cfg.AdminPassword != "correct horse" {
+		t.Errorf("admin = %q, %q, want the trimmed username and the password", cfg.AdminUsername, cfg.AdminPassword)
+	}
+}
@@ -0,0 +1,275 @@
+// Package auth issues and verifies the JWT access tokens and opaque refresh tokens
+// of the API. Access tokens are signed with RS256 when a private key is configured
+// and with HS256 otherwise, tokens of either algorithm are accepted when its key is known.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lynxbites/musiclib"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Issuer is the iss claim of every access token.
+const Issuer = "musiclib"
+
+// ErrPasswordTooLong is returned for passwords bcrypt would silently truncate.
+var ErrPasswordTooLong = errors.New("password is longer than 72 bytes")
+
+type Config struct {
+	// Secret is the HS256 key.
+	Secret []byte
+	// PrivateKeyFile and PublicKeyFile are PEM encoded RSA keys for RS256. The public
+	// key is derived from the private one when only that is given, a public key next
+	// to Secret makes an HS256 signer accept RS256 tokens too.
+	PrivateKeyFile string
+	PublicKeyFile  string
+	// AccessTTL is the lifetime of access tokens, RefreshTTL of refresh tokens.
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// Disabled runs the API without authentication, every request may mutate. Without
+	// it a missing key is a configuration error.
+	Disabled bool
+	// AdminUsername and AdminPassword name the admin account EnsureAdmin creates at
+	// startup, registration only creates viewers. Both or neither are set.
+	AdminUsername string
+	AdminPassword string
+}
+
+// ConfigFromEnv reads JWTSECRET, JWTPRIVATEKEY, JWTPUBLICKEY, JWTACCESSTTL, JWTREFRESHTTL,
+// AUTHDISABLED, ADMINUSERNAME and ADMINPASSWORD.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Secret:         []byte(os.Getenv("JWTSECRET")),
+		PrivateKeyFile: os.Getenv("JWTPRIVATEKEY"),
+		PublicKeyFile:  os.Getenv("JWTPUBLICKEY"),
+		AccessTTL:      15 * time.Minute,
+		RefreshTTL:     30 * 24 * time.Hour,
+		AdminUsername:  strings.TrimSpace(os.Getenv("ADMINUSERNAME")),
+		AdminPassword:  os.Getenv("ADMINPASSWORD"),
+	}
+	durations := map[string]*time.Duration{
+		"JWTACCESSTTL":  &cfg.AccessTTL,
+		"JWTREFRESHTTL": &cfg.RefreshTTL,
+	}
+	for key, dst := range durations {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("parse %s: invalid duration %q", key, v)
+		}
+		*dst = d
+	}
+	if v := os.Getenv("AUTHDISABLED"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("parse AUTHDISABLED: invalid value %q", v)
+		}
+		cfg.Disabled = disabled
+	}
+	if (cfg.AdminUsername == "") != (cfg.AdminPassword == "") {
+		return cfg, errors.New("ADMINUSERNAME and ADMINPASSWORD must be set together")
+	}
+	if cfg.AdminPassword != "" && (utf8.RuneCountInString(cfg.AdminPassword) < 8 || len(cfg.AdminPassword) > 72) {
+		return cfg, errors.New("ADMINPASSWORD must be 8 to 72 bytes")
+	}
+	return cfg, nil
+}
+
+// Claims are the claims of an access token, the subject is the user id.
+type Claims struct {
+	Username string        `json:"username"`
+	Role     musiclib.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// UserId returns the user id held in the subject.
+func (c *Claims) UserId() int {
+	id, _ := strconv.Atoi(c.Subject)
+	return id
+}
+
+// Authenticator signs and verifies access tokens.
+type Authenticator struct {
+	cfg        Config
+	method     jwt.SigningMethod
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	methods    []string
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg}
+
+	if cfg.PrivateKeyFile != "" {
+		pem, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read private key: %w", err)
+		}
+		a.privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		a.publicKey = &a.privateKey.PublicKey
+	}
+	if cfg.PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+		a.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+	}
+
+	switch {
+	case a.privateKey != nil:
+		a.method = jwt.SigningMethodRS256
+	case len(cfg.Secret) > 0:
+		a.method = jwt.SigningMethodHS256
+	default:
+		return nil, errors.New("no signing key: set JWTSECRET or JWTPRIVATEKEY")
+	}
+	if len(cfg.Secret) > 0 && len(cfg.Secret) < 32 {
+		return nil, errors.New("JWTSECRET must be at least 32 bytes")
+	}
+	if len(cfg.Secret) > 0 {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.publicKey != nil {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	return a, nil
+}
+
+// AccessTTL is the lifetime of issued access tokens.
+func (a *Authenticator) AccessTTL() time.Duration {
+	return a.cfg.AccessTTL
+}
+
+// RefreshTTL is the lifetime of issued refresh tokens.
+func (a *Authenticator) RefreshTTL() time.Duration {
+	return a.cfg.RefreshTTL
+}
+
+// IssueAccessToken returns a signed access token of the user.
+func (a *Authenticator) IssueAccessToken(user musiclib.User) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Username: user.Username,
+		Role:     user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   strconv.Itoa(user.Id),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.cfg.AccessTTL)),
+		},
+	}
+
+	var key any = a.cfg.Secret
+	if a.method == jwt.SigningMethodRS256 {
+		key = a.privateKey
+	}
+	token, err := jwt.NewWithClaims(a.method, claims).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("sign access token: %w", err)
+	}
+	return token, nil
+}
+
+// Verify checks the signature, issuer and expiry of an access token and returns its claims.
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, a.key,
+		jwt.WithValidMethods(a.methods),
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserId() <= 0 {
+		return nil, errors.New("token subject is not a user id")
+	}
+	return claims, nil
+}
+
+// key picks the verification key by the token algorithm, WithValidMethods has
+// already rejected algorithms without a configured key.
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.cfg.Secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		return a.publicKey, nil
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// NewRefreshToken returns a random refresh token and the hash to store for it.
+func NewRefreshToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the stored form of a refresh token.
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// apiKeyPrefix marks API keys, so leaked ones are easy to grep for.
+const apiKeyPrefix = "mlk_"
+
+// NewAPIKey returns a random API key, the prefix to show for it and the hash to store.
+func NewAPIKey() (key, prefix string, hash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", nil, fmt.Errorf("generate api key: %w", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(apiKeyPrefix)+6], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the stored form of an API key. Keys are random, so a plain
+// hash is enough.
+func HashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// HashPassword returns the bcrypt hash of a password.
+func HashPassword(password string) (string, error) {
+	if len(password) > 72 {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// APIKeyHeader is the header API keys are sent in.
+const APIKeyHeader = "X-API-Key"
+
+// Principal is who a request is made by, either a user with an access token or a
+// client with an API key.
+type Principal struct {
+	// UserId, Username and Role are set for access tokens.
+	UserId   int
+	Username string
+	Role     musiclib.Role
+	// APIKeyId, Scopes and DailyQuota are set for API keys.
+	APIKeyId   int
+	Scopes     []musiclib.Scope
+	DailyQuota int
+}
+
+// IsUser reports whether the principal is a logged in user rather than an API key.
+func (p *Principal) IsUser() bool {
+	return p.UserId > 0
+}
+
+// Can reports whether the principal has the permission, through its role for users
+// and through its scopes for API keys.
+func (p *Principal) Can(permission Permission) bool {
+	if p.APIKeyId > 0 {
+		for _, scope := range p.Scopes {
+			if ScopeAllows(scope, permission) {
+				return true
+			}
+		}
+		return false
+	}
+	return Allows(p.Role, permission)
+}
+
+type contextKey struct{}
+
+// FromContext returns the principal of the authenticated request, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
+
+// WithPrincipal returns a copy of ctx carrying principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// Authenticate reads a bearer token from the Authorization header and puts its principal
+// into the request context. Requests without the header pass through anonymously,
+// a malformed or invalid token is rejected with 401.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			Unauthorized(w, r, "invalid_request")
+			return
+		}
+		claims, err := a.Verify(strings.TrimSpace(token))
+		if err != nil {
+			log.Debug("Rejected access token", "err", err)
+			Unauthorized(w, r, "invalid_token")
+			return
+		}
+		principal := &Principal{UserId: claims.UserId(), Username: claims.Username, Role: claims.Role}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// AuthenticateAPIKey reads an API key from the X-API-Key header and puts its principal
+// into the request context. Requests without the header pass through, unknown keys and
+// requests that also carry an Authorization header are rejected with 401.
+func AuthenticateAPIKey(keys musiclib.APIKeyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(APIKeyHeader)
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Header.Get("Authorization") != "" {
+				log.Debug("401 Unauthorized: Both API key and bearer token")
+				problem.Error(w, r, 401, "Send either an Authorization or an X-API-Key header")
+				return
+			}
+			key, err := keys.Use(r.Context(), HashAPIKey(header))
+			if errors.Is(err, musiclib.ErrTokenInvalid) {
+				log.Debug("401 Unauthorized: Invalid API key")
+				problem.Error(w, r, 401, "Invalid API key")
+				return
+			}
+			if err != nil {
+				log.Error("Encountered error when trying to check API key: %v", err)
+				problem.Internal(w, r)
+				return
+			}
+			principal := &Principal{APIKeyId: key.Id, Scopes: key.Scopes, DailyQuota: key.DailyQuota}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireUser rejects requests without a logged in user.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := FromContext(r.Context()); !ok || !principal.IsUser() {
+			Unauthorized(w, r, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Unauthorized writes a 401 response with a Bearer challenge, errorCode is the
+// RFC 6750 error and may be empty when no token was sent.
+func Unauthorized(w http.ResponseWriter, r *http.Request, errorCode string) {
+	challenge := `Bearer realm="musiclib"`
+	if errorCode != "" {
+		challenge += `, error="` + errorCode + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	log.Debug("401 Unauthorized")
+	detail := "Authentication is required"
+	if errorCode == "invalid_token" {
+		detail = "The access token is invalid or expired"
+	} else if errorCode == "invalid_request" {
+		detail = "The Authorization header must be \"Bearer <token>\""
+	}
+	problem.Error(w, r, 401, detail)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// Permission names an action guarded by RequirePermission.
+type Permission string
+
+const (
+	// PermissionReadSongs allows listing and reading songs. Every role has it and
+	// anonymous reads are allowed, it only restricts API keys.
+	PermissionReadSongs Permission = "songs:read"
+	// PermissionWriteSongs allows adding and patching songs.
+	PermissionWriteSongs Permission = "songs:write"
+	// PermissionDeleteSongs allows deleting songs.
+	PermissionDeleteSongs Permission = "songs:delete"
+	// PermissionWriteLibrary allows creating and editing groups, albums and playlists,
+	// including their tracks and entries.
+	PermissionWriteLibrary Permission = "library:write"
+	// PermissionWritePlaylists allows creating playlists and editing and deleting the
+	// playlists the user owns, other playlists need the library permissions.
+	PermissionWritePlaylists Permission = "playlists:write"
+	// PermissionDeleteLibrary allows deleting groups, albums and playlists.
+	PermissionDeleteLibrary Permission = "library:delete"
+	// PermissionManageUsers allows listing users and assigning roles.
+	PermissionManageUsers Permission = "users:manage"
+	// PermissionManageAPIKeys allows minting, listing, rotating and revoking API keys.
+	PermissionManageAPIKeys Permission = "apikeys:manage"
+)
+
+var rolePermissions = map[musiclib.Role][]Permission{
+	musiclib.RoleViewer: {PermissionReadSongs, PermissionWritePlaylists},
+	musiclib.RoleEditor: {PermissionReadSongs, PermissionWriteSongs, PermissionWriteLibrary, PermissionWritePlaylists},
+	musiclib.RoleAdmin: {PermissionReadSongs, PermissionWriteSongs, PermissionDeleteSongs, PermissionWriteLibrary,
+		PermissionDeleteLibrary, PermissionWritePlaylists, PermissionManageUsers, PermissionManageAPIKeys},
+}
+
+var scopePermissions = map[musiclib.Scope][]Permission{
+	musiclib.ScopeReadSongs:  {PermissionReadSongs},
+	musiclib.ScopeWriteSongs: {PermissionWriteSongs},
+}
+
+// Allows reports whether the role grants the permission, unknown roles grant nothing.
+func Allows(role musiclib.Role, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// ScopeAllows reports whether the API key scope grants the permission.
+func ScopeAllows(scope musiclib.Scope, permission Permission) bool {
+	return slices.Contains(scopePermissions[scope], permission)
+}
+
+// RolesWith returns the roles that grant the permission.
+func RolesWith(permission Permission) []musiclib.Role {
+	var roles []musiclib.Role
+	for _, role := range []musiclib.Role{musiclib.RoleViewer, musiclib.RoleEditor, musiclib.RoleAdmin} {
+		if Allows(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// ScopesWith returns the API key scopes that grant the permission.
+func ScopesWith(permission Permission) []musiclib.Scope {
+	var scopes []musiclib.Scope
+	for _, scope := range []musiclib.Scope{musiclib.ScopeReadSongs, musiclib.ScopeWriteSongs} {
+		if ScopeAllows(scope, permission) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// RequirePermission rejects anonymous requests with 401 and requests whose principal
+// lacks the permission with 403. The role of a user is read from the access token, so
+// a changed role applies once the user gets a new one.
+func RequirePermission(permission Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if !ok {
+				Unauthorized(w, r, "")
+				return
+			}
+			if !principal.Can(permission) {
+				Forbidden(w, r, principal, permission)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CheckPermission is RequirePermission for routes open to anonymous requests, it only
+// rejects authenticated principals that lack the permission.
+func CheckPermission(permission Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := FromContext(r.Context())
+			if ok && !principal.Can(permission) {
+				Forbidden(w, r, principal, permission)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Forbidden writes a 403 response naming the missing permission and the roles or
+// scopes that have it.
+func Forbidden(w http.ResponseWriter, r *http.Request, principal *Principal, permission Permission) {
+	var message string
+	if principal.APIKeyId > 0 {
+		var scopes []string
+		for _, s := range ScopesWith(permission) {
+			scopes = append(scopes, string(s))
+		}
+		message = fmt.Sprintf("API key lacks permission %q", permission)
+		if len(scopes) > 0 {
+			message += ", which is granted by scope " + strings.Join(scopes, " or ")
+		} else {
+			message += ", which no API key scope grants"
+		}
+	} else {
+		var roles []string
+		for _, r := range RolesWith(permission) {
+			roles = append(roles, string(r))
+		}
+		message = fmt.Sprintf("Role %q lacks permission %q, which is granted to %s", principal.Role, permission, strings.Join(roles, " and "))
+	}
+	log.Debug("403 Forbidden: " + message)
+	problem.Error(w, r, 403, message)
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+func TestAllows(t *testing.T) {
+	permissions := []Permission{
+		PermissionReadSongs,
+		PermissionWriteSongs,
+		PermissionDeleteSongs,
+		PermissionWriteLibrary,
+		PermissionDeleteLibrary,
+		PermissionWritePlaylists,
+		PermissionManageUsers,
+		PermissionManageAPIKeys,
+	}
+	granted := map[musiclib.Role][]Permission{
+		musiclib.RoleViewer: {PermissionReadSongs, PermissionWritePlaylists},
+		musiclib.RoleEditor: {PermissionReadSongs, PermissionWriteSongs, PermissionWriteLibrary, PermissionWritePlaylists},
+		musiclib.RoleAdmin:  permissions,
+		"unknown":           nil,
+	}
+
+	for role, want := range granted {
+		for _, permission := range permissions {
+			wantAllowed := slices.Contains(want, permission)
+			if got := Allows(role, permission); got != wantAllowed {
+				t.Errorf("Allows(%q, %q) = %v, want %v", role, permission, got, wantAllowed)
+			}
+		}
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope      musiclib.Scope
+		permission Permission
+		want       bool
+	}{
+		{musiclib.ScopeReadSongs, PermissionReadSongs, true},
+		{musiclib.ScopeReadSongs, PermissionWriteSongs, false},
+		{musiclib.ScopeWriteSongs, PermissionWriteSongs, true},
+		{musiclib.ScopeWriteSongs, PermissionReadSongs, false},
+		{musiclib.ScopeWriteSongs, PermissionDeleteSongs, false},
+		{musiclib.ScopeWriteSongs, PermissionWritePlaylists, false},
+		{musiclib.ScopeReadSongs, PermissionWriteLibrary, false},
+	}
+	for _, tt := range tests {
+		if got := ScopeAllows(tt.scope, tt.permission); got != tt.want {
+			t.Errorf("ScopeAllows(%q, %q) = %v, want %v", tt.scope, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	viewer := &Principal{UserId: 1, Role: musiclib.RoleViewer}
+	if !viewer.Can(PermissionWritePlaylists) {
+		t.Error("viewer cannot write playlists")
+	}
+	if viewer.Can(PermissionWriteLibrary) {
+		t.Error("viewer can write the library")
+	}
+
+	// An API key is limited to its scopes whatever the role of its owner.
+	key := &Principal{APIKeyId: 1, Role: musiclib.RoleAdmin, Scopes: []musiclib.Scope{musiclib.ScopeReadSongs}}
+	if !key.Can(PermissionReadSongs) {
+		t.Error("read scope cannot read songs")
+	}
+	if key.Can(PermissionWriteSongs) {
+		t.Error("read scope can write songs")
+	}
+}
+
+func TestRolesWith(t *testing.T) {
+	roles := RolesWith(PermissionWritePlaylists)
+	want := []musiclib.Role{musiclib.RoleViewer, musiclib.RoleEditor, musiclib.RoleAdmin}
+	if !slices.Equal(roles, want) {
+		t.Errorf("RolesWith(%q) = %v, want %v", PermissionWritePlaylists, roles, want)
+	}
+}
+
+// servePermission runs a request as principal, nil means anonymous, through the middleware.
+func servePermission(middleware func(http.Handler) http.Handler, principal *Principal) *httptest.ResponseRecorder {
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	r := httptest.NewRequest("POST", "/api/v1/songs/", nil)
+	if principal != nil {
+		r = r.WithContext(WithPrincipal(r.Context(), principal))
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRequirePermission(t *testing.T) {
+	editor := &Principal{UserId: 1, Role: musiclib.RoleEditor}
+	viewer := &Principal{UserId: 2, Role: musiclib.RoleViewer}
+	readKey := &Principal{APIKeyId: 1, Scopes: []musiclib.Scope{musiclib.ScopeReadSongs}}
+	middleware := RequirePermission(PermissionWriteSongs)
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		status    int
+		detail    string
+	}{
+		{"anonymous", nil, 401, "Authentication is required"},
+		{"viewer", viewer, 403, `Role "viewer" lacks permission "songs:write", which is granted to editor and admin`},
+		{"read key", readKey, 403, `API key lacks permission "songs:write", which is granted by scope write:songs`},
+		{"editor", editor, 204, ""},
+	}
+	for _, tt := range tests {
+		w := servePermission(middleware, tt.principal)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.detail == "" {
+			continue
+		}
+		var p problem.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: decode %s: %v", tt.name, w.Body, err)
+		}
+		if p.Detail != tt.detail {
+			t.Errorf("%s: detail = %q, want %q", tt.name, p.Detail, tt.detail)
+		}
+	}
+
+	w := servePermission(middleware, nil)
+	if got := w.Header().Get("WWW-Authenticate"); got != `Bearer realm="musiclib"` {
+		t.Errorf("WWW-Authenticate = %q, want the bearer challenge", got)
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	middleware := CheckPermission(PermissionReadSongs)
+
+	// Anonymous requests pass, only principals without the permission are rejected.
+	if w := servePermission(middleware, nil); w.Code != 204 {
+		t.Errorf("anonymous: status = %d, want 204", w.Code)
+	}
+	if w := servePermission(middleware, &Principal{UserId: 1, Role: musiclib.RoleViewer}); w.Code != 204 {
+		t.Errorf("viewer: status = %d, want 204", w.Code)
+	}
+	writeKey := &Principal{APIKeyId: 1, Scopes: []musiclib.Scope{musiclib.ScopeWriteSongs}}
+	w := servePermission(middleware, writeKey)
+	if w.Code != 403 {
+		t.Fatalf("write key: status = %d, want 403", w.Code)
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	if want := `API key lacks permission "songs:read", which is granted by scope read:songs`; p.Detail != want {
+		t.Errorf("detail = %q, want %q", p.Detail, want)
+	}
+}
+
+func TestForbiddenWithoutScope(t *testing.T) {
+	w := httptest.NewRecorder()
+	key := &Principal{APIKeyId: 1, Scopes: []musiclib.Scope{musiclib.ScopeWriteSongs}}
+	Forbidden(w, httptest.NewRequest("DELETE", "/api/v1/songs/1", nil), key, PermissionDeleteSongs)
+
+	if w.Code != 403 || w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("status = %d, Content-Type = %q, want a 403 problem", w.Code, w.Header().Get("Content-Type"))
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	if want := `API key lacks permission "songs:delete", which no API key scope grants`; p.Detail != want {
+		t.Errorf("detail = %q, want %q", p.Detail, want)
+	}
+}
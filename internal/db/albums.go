@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lynxbites/musiclib"
+)
+
+// AlbumStore is the Postgres implementation of musiclib.AlbumStore.
+type AlbumStore struct {
+	db *DB
+}
+
+var _ musiclib.AlbumStore = (*AlbumStore)(nil)
+
+func NewAlbumStore(db *DB) *AlbumStore {
+	return &AlbumStore{db: db}
+}
+
+// albumColumns is the column list scanned by scanAlbum, selected from albumsFrom.
+const albumColumns = "albumId, title, groupId, groupName, releaseDate, releaseDatePrecision, coverLink, (select count(*) from tracks where tracks.albumId = albums.albumId)"
+
+const albumsFrom = " from albums join groups using (groupId)"
+
+func scanAlbum(row pgx.Row) (musiclib.Album, error) {
+	var album musiclib.Album
+	var releaseDate releaseDateColumns
+	err := row.Scan(&album.Id, &album.Title, &album.GroupId, &album.Group, &releaseDate.date, &releaseDate.precision, &album.CoverLink, &album.TrackCount)
+	album.ReleaseDate = releaseDate.value()
+	return album, err
+}
+
+// lockAlbum locks the album row, so track list changes of one album run one at a time.
+func lockAlbum(ctx context.Context, tx pgx.Tx, id int) error {
+	var locked int
+	err := tx.QueryRow(ctx, "select albumId from albums where albumId = $1 for update", id).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return musiclib.ErrAlbumNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock album %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *AlbumStore) List(ctx context.Context, opts musiclib.AlbumListOptions) ([]musiclib.Album, error) {
+	where := &whereBuilder{}
+	if opts.GroupId != 0 {
+		where.add("groupId = ?", opts.GroupId)
+	}
+	query := "select " + albumColumns + albumsFrom + where.sql() + " order by groupName, " + releaseDateSortExpr + ", title, albumId"
+
+	args := where.args
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query albums: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []musiclib.Album
+	for rows.Next() {
+		album, err := scanAlbum(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan album: %w", err)
+		}
+		albums = append(albums, album)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read albums: %w", err)
+	}
+	return albums, nil
+}
+
+func (s *AlbumStore) Count(ctx context.Context, groupId int) (int, error) {
+	where := &whereBuilder{}
+	if groupId != 0 {
+		where.add("groupId = ?", groupId)
+	}
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from albums"+where.sql(), where.args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count albums: %w", err)
+	}
+	return count, nil
+}
+
+func (s *AlbumStore) Get(ctx context.Context, id int) (musiclib.Album, error) {
+	album, err := scanAlbum(s.db.QueryRow(ctx, "select "+albumColumns+albumsFrom+" where albumId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return album, musiclib.ErrAlbumNotFound
+	}
+	if err != nil {
+		return album, fmt.Errorf("query album %d: %w", id, err)
+	}
+
+	rows, err := s.db.Query(ctx, "select "+songColumns+", disc, number from tracks join songs using (songId) join groups using (groupId) where albumId = $1 order by disc, number", id)
+	if err != nil {
+		return album, fmt.Errorf("query tracks of album %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	album.Tracks = []musiclib.Track{}
+	for rows.Next() {
+		var track musiclib.Track
+		track.Song, err = scanSong(rows, &track.Disc, &track.Number)
+		if err != nil {
+			return album, fmt.Errorf("scan track: %w", err)
+		}
+		album.Tracks = append(album.Tracks, track)
+	}
+	if err := rows.Err(); err != nil {
+		return album, fmt.Errorf("read tracks: %w", err)
+	}
+	return album, nil
+}
+
+func (s *AlbumStore) Create(ctx context.Context, album musiclib.Album) (musiclib.Album, error) {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, resolveGroupQuery, album.Group).Scan(&album.GroupId)
+		if err != nil {
+			return fmt.Errorf("resolve group: %w", err)
+		}
+
+		releaseDate := newReleaseDateColumns(album.ReleaseDate)
+		err = tx.QueryRow(ctx, `insert into albums (groupId, title, releaseDate, releaseDatePrecision, coverLink) values ($1,$2,$3,$4,$5) returning albumId`,
+			album.GroupId, album.Title, releaseDate.date, releaseDate.precision, album.CoverLink).Scan(&album.Id)
+		if isPgError(err, pgUniqueViolation) {
+			return musiclib.ErrAlbumConflict
+		}
+		if err != nil {
+			return fmt.Errorf("insert album: %w", err)
+		}
+		return nil
+	})
+	return album, err
+}
+
+func (s *AlbumStore) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, "delete from albums where albumId = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete album %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrAlbumNotFound
+	}
+	return nil
+}
+
+func (s *AlbumStore) AddTrack(ctx context.Context, albumId int, track musiclib.TrackPosition) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockAlbum(ctx, tx, albumId); err != nil {
+			return err
+		}
+		disc := max(track.Disc, 1)
+
+		var next int
+		err := tx.QueryRow(ctx, "select coalesce(max(number), 0) + 1 from tracks where albumId = $1 and disc = $2", albumId, disc).Scan(&next)
+		if err != nil {
+			return fmt.Errorf("query track numbers: %w", err)
+		}
+		number := next
+		if track.Number > 0 && track.Number < next {
+			number = track.Number
+			_, err = tx.Exec(ctx, "update tracks set number = number + 1 where albumId = $1 and disc = $2 and number >= $3", albumId, disc, number)
+			if err != nil {
+				return fmt.Errorf("shift tracks: %w", err)
+			}
+		}
+
+		_, err = tx.Exec(ctx, "insert into tracks (albumId, songId, disc, number) values ($1, $2, $3, $4)", albumId, track.SongId, disc, number)
+		if isPgError(err, pgUniqueViolation) {
+			return musiclib.ErrTrackConflict
+		}
+		if isPgError(err, pgForeignKeyViolation) {
+			return musiclib.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("insert track: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *AlbumStore) SetTracks(ctx context.Context, albumId int, tracks []musiclib.TrackPosition) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockAlbum(ctx, tx, albumId); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "delete from tracks where albumId = $1", albumId)
+		if err != nil {
+			return fmt.Errorf("clear tracks: %w", err)
+		}
+
+		numbers := make(map[int]int)
+		for _, track := range tracks {
+			disc := max(track.Disc, 1)
+			numbers[disc]++
+			_, err = tx.Exec(ctx, "insert into tracks (albumId, songId, disc, number) values ($1, $2, $3, $4)", albumId, track.SongId, disc, numbers[disc])
+			if isPgError(err, pgUniqueViolation) {
+				return musiclib.ErrTrackConflict
+			}
+			if isPgError(err, pgForeignKeyViolation) {
+				return musiclib.ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("insert track: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *AlbumStore) RemoveTrack(ctx context.Context, albumId, songId int) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockAlbum(ctx, tx, albumId); err != nil {
+			return err
+		}
+
+		var disc, number int
+		err := tx.QueryRow(ctx, "delete from tracks where albumId = $1 and songId = $2 returning disc, number", albumId, songId).Scan(&disc, &number)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrTrackNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("delete track: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, "update tracks set number = number - 1 where albumId = $1 and disc = $2 and number > $3", albumId, disc, number)
+		if err != nil {
+			return fmt.Errorf("shift tracks: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lynxbites/musiclib"
+)
+
+// apiKeyUseInterval is how stale lastUsedAt may get before Use writes it again,
+// so busy clients do not update the row on every request.
+const apiKeyUseInterval = time.Minute
+
+// APIKeyStore is the Postgres implementation of musiclib.APIKeyStore.
+type APIKeyStore struct {
+	db *DB
+}
+
+var _ musiclib.APIKeyStore = (*APIKeyStore)(nil)
+
+func NewAPIKeyStore(db *DB) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+// apiKeyColumns is the column list scanned by scanAPIKey.
+const apiKeyColumns = "apiKeyId, name, prefix, keyHash, scopes, coalesce(createdBy, 0), createdAt, lastUsedAt, coalesce(dailyQuota, 0), " +
+	"coalesce((select requests from apiKeyUsage u where u.apiKeyId = apiKeys.apiKeyId and u.day = " + usageDay + "), 0)"
+
+// usageDay is the UTC day quotas are counted in.
+const usageDay = "(now() at time zone 'utc')::date"
+
+func scanAPIKey(row pgx.Row) (musiclib.APIKey, error) {
+	var key musiclib.APIKey
+	var scopes []string
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedBy, &key.CreatedAt, &key.LastUsedAt, &key.DailyQuota, &key.UsedToday)
+	key.Scopes = make([]musiclib.Scope, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = musiclib.Scope(scope)
+	}
+	return key, err
+}
+
+func scopeStrings(scopes []musiclib.Scope) []string {
+	strs := make([]string, len(scopes))
+	for i, scope := range scopes {
+		strs[i] = string(scope)
+	}
+	return strs
+}
+
+func (s *APIKeyStore) List(ctx context.Context, limit, offset int) ([]musiclib.APIKey, error) {
+	query := "select " + apiKeyColumns + " from apiKeys order by apiKeyId"
+	var args []any
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []musiclib.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from apiKeys").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count api keys: %w", err)
+	}
+	return count, nil
+}
+
+func (s *APIKeyStore) Get(ctx context.Context, id int) (musiclib.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, "select "+apiKeyColumns+" from apiKeys where apiKeyId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, musiclib.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return key, fmt.Errorf("query api key %d: %w", id, err)
+	}
+	return key, nil
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, key musiclib.APIKey) (musiclib.APIKey, error) {
+	var createdBy *int
+	if key.CreatedBy > 0 {
+		createdBy = &key.CreatedBy
+	}
+	created, err := scanAPIKey(s.db.QueryRow(ctx, "insert into apiKeys (name, prefix, keyHash, scopes, createdBy, dailyQuota) values ($1, $2, $3, $4, $5, nullif($6, 0)) returning "+apiKeyColumns,
+		key.Name, key.Prefix, key.KeyHash, scopeStrings(key.Scopes), createdBy, key.DailyQuota))
+	if err != nil {
+		return key, fmt.Errorf("insert api key: %w", err)
+	}
+	return created, nil
+}
+
+func (s *APIKeyStore) Update(ctx context.Context, key musiclib.APIKey) error {
+	tag, err := s.db.Exec(ctx, "update apiKeys set name = $2, dailyQuota = nullif($3, 0) where apiKeyId = $1", key.Id, key.Name, key.DailyQuota)
+	if err != nil {
+		return fmt.Errorf("update api key %d: %w", key.Id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *APIKeyStore) Rotate(ctx context.Context, id int, prefix string, keyHash []byte) (musiclib.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, "update apiKeys set prefix = $2, keyHash = $3, lastUsedAt = null where apiKeyId = $1 returning "+apiKeyColumns, id, prefix, keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, musiclib.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return key, fmt.Errorf("rotate api key %d: %w", id, err)
+	}
+	return key, nil
+}
+
+func (s *APIKeyStore) Delete(ctx context.Context, id int) error {
+	_, err := s.db.Exec(ctx, "delete from apiKeys where apiKeyId = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete api key %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *APIKeyStore) Use(ctx context.Context, keyHash []byte) (musiclib.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRow(ctx, "select "+apiKeyColumns+" from apiKeys where keyHash = $1", keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return key, musiclib.ErrTokenInvalid
+	}
+	if err != nil {
+		return key, fmt.Errorf("query api key: %w", err)
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyUseInterval {
+		err = s.db.QueryRow(ctx, "update apiKeys set lastUsedAt = now() where apiKeyId = $1 returning lastUsedAt", key.Id).Scan(&key.LastUsedAt)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return key, fmt.Errorf("update api key %d: %w", key.Id, err)
+		}
+	}
+	return key, nil
+}
+
+// UseQuota counts with a single upsert that stops incrementing at the quota, so
+// concurrent requests cannot overshoot it.
+func (s *APIKeyStore) UseQuota(ctx context.Context, id, quota int) (int, error) {
+	var requests int
+	err := s.db.QueryRow(ctx, `insert into apiKeyUsage (apiKeyId, day, requests) values ($1, `+usageDay+`, 1)
+on conflict (apiKeyId, day) do update set requests = apiKeyUsage.requests + 1
+where apiKeyUsage.requests < $2
+returning requests`, id, quota).Scan(&requests)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quota, musiclib.ErrQuotaExceeded
+	}
+	if err != nil {
+		return 0, fmt.Errorf("count api key %d usage: %w", id, err)
+	}
+	return requests, nil
+}
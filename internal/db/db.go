@@ -4,18 +4,31 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/charmbracelet/log"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
 
+// DB is a long-lived connection pool shared by all handlers.
 type DB struct {
-	*pgx.Conn
+	*pgxpool.Pool
+}
+
+// Config holds pool settings, zero values fall back to pgxpool defaults.
+type Config struct {
+	ConnString        string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
 }
 
 var connstr string
@@ -34,21 +47,91 @@ func init() {
 		log.Fatal("lookup connection string: connection string not specified in .env file")
 	}
 	connstrm, connstrfound = os.LookupEnv("CONNSTRMIGRATION")
-	if connstr == "" || connstrfound == false {
+	if connstrm == "" || connstrfound == false {
 		log.Fatal("lookup connection string: connection string not specified in .env file")
 	}
 }
 
-func New() *DB {
+// ConfigFromEnv builds pool config from CONNSTR and the optional DBMAXCONNS,
+// DBMINCONNS, DBMAXCONNLIFETIME, DBMAXCONNIDLETIME and DBHEALTHCHECKPERIOD variables.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{ConnString: connstr}
+
+	if v, ok := os.LookupEnv("DBMAXCONNS"); ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("parse DBMAXCONNS: invalid value %q", v)
+		}
+		cfg.MaxConns = int32(n)
+	}
+	if v, ok := os.LookupEnv("DBMINCONNS"); ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("parse DBMINCONNS: invalid value %q", v)
+		}
+		cfg.MinConns = int32(n)
+	}
+
+	durations := map[string]*time.Duration{
+		"DBMAXCONNLIFETIME":   &cfg.MaxConnLifetime,
+		"DBMAXCONNIDLETIME":   &cfg.MaxConnIdleTime,
+		"DBHEALTHCHECKPERIOD": &cfg.HealthCheckPeriod,
+	}
+	for key, dst := range durations {
+		v, ok := os.LookupEnv(key)
+		if !ok || v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("parse %s: invalid duration %q", key, v)
+		}
+		*dst = d
+	}
+
+	return cfg, nil
+}
 
-	conn, err := pgx.Connect(context.Background(), connstr)
+// New creates the connection pool. Connections are opened lazily, so an
+// unreachable database shows up as query errors instead of stopping the server.
+func New(ctx context.Context, cfg Config) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnString)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("parse connection string: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create connection pool: %w", err)
 	}
 	return &DB{
-		conn,
+		pool,
+	}, nil
+}
+
+// Health checks that a pooled connection can reach the database.
+func (db *DB) Health(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
 	}
+	return nil
 }
 
 func Migration() (*migrate.Migrate, error) {
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lynxbites/musiclib"
+)
+
+// Postgres error codes the stores translate into musiclib errors.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// isPgConstraintError is isPgError for errors about one constraint or unique index.
+func isPgConstraintError(err error, code, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code && pgErr.ConstraintName == constraint
+}
+
+// GroupStore is the Postgres implementation of musiclib.GroupStore.
+type GroupStore struct {
+	db *DB
+}
+
+var _ musiclib.GroupStore = (*GroupStore)(nil)
+
+func NewGroupStore(db *DB) *GroupStore {
+	return &GroupStore{db: db}
+}
+
+// groupColumns is the column list scanned by scanGroup.
+const groupColumns = "groupId, groupName, (select count(*) from songs where songs.groupId = groups.groupId)"
+
+func scanGroup(row pgx.Row) (musiclib.Group, error) {
+	var group musiclib.Group
+	err := row.Scan(&group.Id, &group.Name, &group.SongCount)
+	return group, err
+}
+
+// resolveGroupQuery returns the id of the group with the name in $1, creating it when missing.
+// The no-op update makes the existing row come back from returning.
+const resolveGroupQuery = `insert into groups (groupName) values ($1)
+on conflict ((lower(groupName))) do update set groupName = groups.groupName
+returning groupId`
+
+func (s *GroupStore) List(ctx context.Context, limit, offset int) ([]musiclib.Group, error) {
+	query := "select " + groupColumns + " from groups order by groupName, groupId"
+	var args []any
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []musiclib.Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read groups: %w", err)
+	}
+	return groups, nil
+}
+
+func (s *GroupStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from groups").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count groups: %w", err)
+	}
+	return count, nil
+}
+
+func (s *GroupStore) Get(ctx context.Context, id int) (musiclib.Group, error) {
+	group, err := scanGroup(s.db.QueryRow(ctx, "select "+groupColumns+" from groups where groupId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return group, musiclib.ErrGroupNotFound
+	}
+	if err != nil {
+		return group, fmt.Errorf("query group %d: %w", id, err)
+	}
+	return group, nil
+}
+
+func (s *GroupStore) Create(ctx context.Context, name string) (musiclib.Group, error) {
+	group := musiclib.Group{Name: name}
+	err := s.db.QueryRow(ctx, "insert into groups (groupName) values ($1) returning groupId", name).Scan(&group.Id)
+	if isPgError(err, pgUniqueViolation) {
+		return group, musiclib.ErrGroupConflict
+	}
+	if err != nil {
+		return group, fmt.Errorf("insert group: %w", err)
+	}
+	return group, nil
+}
+
+// Update renames the group, its songs get a new version since they show the group name.
+func (s *GroupStore) Update(ctx context.Context, group musiclib.Group) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "update groups set groupName = $1 where groupId = $2", group.Name, group.Id)
+		if isPgError(err, pgUniqueViolation) {
+			return musiclib.ErrGroupConflict
+		}
+		if err != nil {
+			return fmt.Errorf("update group %d: %w", group.Id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return musiclib.ErrGroupNotFound
+		}
+
+		_, err = tx.Exec(ctx, "update songs set version = nextval('songVersions'), updatedAt = now() where groupId = $1", group.Id)
+		if err != nil {
+			return fmt.Errorf("touch songs of group %d: %w", group.Id, err)
+		}
+		return nil
+	})
+}
+
+func (s *GroupStore) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, "delete from groups where groupId = $1", id)
+	if isPgError(err, pgForeignKeyViolation) {
+		return musiclib.ErrGroupNotEmpty
+	}
+	if err != nil {
+		return fmt.Errorf("delete group %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrGroupNotFound
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lynxbites/musiclib"
+)
+
+// JobQueue is the Postgres implementation of musiclib.JobQueue, workers claim jobs
+// with FOR UPDATE SKIP LOCKED so any number of them can share the table.
+type JobQueue struct {
+	db          *DB
+	maxAttempts int
+}
+
+var _ musiclib.JobQueue = (*JobQueue)(nil)
+
+func NewJobQueue(db *DB, maxAttempts int) *JobQueue {
+	return &JobQueue{db: db, maxAttempts: maxAttempts}
+}
+
+const jobColumns = "jobId, kind, songId, status, attempts, maxAttempts, runAt, lastError, createdAt, updatedAt"
+
+func scanJob(row pgx.Row) (musiclib.Job, error) {
+	var job musiclib.Job
+	var lastError pgtype.Text
+	err := row.Scan(&job.Id, &job.Kind, &job.SongId, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &lastError, &job.CreatedAt, &job.UpdatedAt)
+	job.LastError = lastError.String
+	return job, err
+}
+
+func (q *JobQueue) Enqueue(ctx context.Context, kind string, songId int) (musiclib.Job, error) {
+	job, err := scanJob(q.db.QueryRow(ctx, "insert into jobs (kind, songId, maxAttempts) values ($1, $2, $3) returning "+jobColumns, kind, songId, q.maxAttempts))
+	if err != nil {
+		return job, fmt.Errorf("enqueue job: %w", err)
+	}
+	return job, nil
+}
+
+func (q *JobQueue) Get(ctx context.Context, id int64) (musiclib.Job, error) {
+	job, err := scanJob(q.db.QueryRow(ctx, "select "+jobColumns+" from jobs where jobId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, musiclib.ErrJobNotFound
+	}
+	if err != nil {
+		return job, fmt.Errorf("query job %d: %w", id, err)
+	}
+	return job, nil
+}
+
+func (q *JobQueue) Claim(ctx context.Context, lease time.Duration) (musiclib.Job, bool, error) {
+	job, err := scanJob(q.db.QueryRow(ctx, `update jobs
+set status = 'running', attempts = attempts + 1, lockedUntil = now() + $1::interval, updatedAt = now()
+where jobId = (
+	select jobId from jobs
+	where (status = 'queued' and runAt <= now()) or (status = 'running' and lockedUntil < now())
+	order by runAt, jobId
+	for update skip locked
+	limit 1
+)
+returning `+jobColumns, lease))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return job, false, nil
+	}
+	if err != nil {
+		return job, false, fmt.Errorf("claim job: %w", err)
+	}
+	return job, true, nil
+}
+
+func (q *JobQueue) Complete(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, "update jobs set status = 'done', lockedUntil = null, lastError = null, updatedAt = now() where jobId = $1", id)
+	if err != nil {
+		return fmt.Errorf("complete job %d: %w", id, err)
+	}
+	return nil
+}
+
+func (q *JobQueue) Fail(ctx context.Context, id int64, reason string, retryAt time.Time, permanent bool) error {
+	_, err := q.db.Exec(ctx, `update jobs
+set status = case when $4 or attempts >= maxAttempts then 'dead' else 'queued' end,
+	runAt = $2, lastError = $3, lockedUntil = null, updatedAt = now()
+where jobId = $1`, id, retryAt, reason, permanent)
+	if err != nil {
+		return fmt.Errorf("fail job %d: %w", id, err)
+	}
+	return nil
+}
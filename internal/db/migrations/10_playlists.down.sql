@@ -0,0 +1,2 @@
+DROP TABLE playlistEntries;
+DROP TABLE playlists;
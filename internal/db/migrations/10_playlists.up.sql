@@ -0,0 +1,20 @@
+CREATE TABLE playlists (
+    playlistId      INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name            TEXT NOT NULL,
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updatedAt       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX playlists_name_idx ON playlists (name, playlistId);
+
+-- The position constraint is deferrable so a single UPDATE can shift positions.
+CREATE TABLE playlistEntries (
+    entryId         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    playlistId      INTEGER NOT NULL REFERENCES playlists (playlistId) ON DELETE CASCADE,
+    songId          INTEGER NOT NULL REFERENCES songs (songId) ON DELETE CASCADE,
+    position        INTEGER NOT NULL CHECK (position > 0),
+    addedAt         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT playlist_entries_position_key UNIQUE (playlistId, position) DEFERRABLE
+);
+
+CREATE INDEX playlist_entries_song_idx ON playlistEntries (songId);
@@ -0,0 +1,9 @@
+CREATE TABLE smartPlaylists (
+    smartPlaylistId INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name            TEXT NOT NULL,
+    rules           JSONB NOT NULL DEFAULT '[]' CHECK (jsonb_typeof(rules) = 'array'),
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updatedAt       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX smart_playlists_name_idx ON smartPlaylists (name, smartPlaylistId);
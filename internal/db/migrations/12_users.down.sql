@@ -0,0 +1,2 @@
+DROP TABLE refreshTokens;
+DROP TABLE users;
@@ -0,0 +1,18 @@
+CREATE TABLE users (
+    userId          INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    username        TEXT NOT NULL,
+    passwordHash    TEXT NOT NULL,
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX users_username_key ON users (lower(username));
+
+CREATE TABLE refreshTokens (
+    tokenHash       BYTEA PRIMARY KEY,
+    userId          INTEGER NOT NULL REFERENCES users (userId) ON DELETE CASCADE,
+    expiresAt       TIMESTAMPTZ NOT NULL,
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX refresh_tokens_user_idx ON refreshTokens (userId);
+CREATE INDEX refresh_tokens_expires_idx ON refreshTokens (expiresAt);
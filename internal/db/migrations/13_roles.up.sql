@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'viewer'
+    CONSTRAINT users_role_check CHECK (role IN ('viewer', 'editor', 'admin'));
+
+UPDATE users SET role = 'admin' WHERE userId = (SELECT min(userId) FROM users);
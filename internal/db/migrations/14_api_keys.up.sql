@@ -0,0 +1,11 @@
+CREATE TABLE apiKeys (
+    apiKeyId        INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name            TEXT NOT NULL,
+    prefix          TEXT NOT NULL,
+    keyHash         BYTEA NOT NULL UNIQUE,
+    scopes          TEXT[] NOT NULL DEFAULT '{}'
+        CONSTRAINT api_keys_scopes_check CHECK (scopes <@ ARRAY['read:songs', 'write:songs']),
+    createdBy       INTEGER REFERENCES users (userId) ON DELETE SET NULL,
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    lastUsedAt      TIMESTAMPTZ
+);
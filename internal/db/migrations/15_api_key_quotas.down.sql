@@ -0,0 +1,2 @@
+DROP TABLE apiKeyUsage;
+ALTER TABLE apiKeys DROP COLUMN dailyQuota;
@@ -0,0 +1,9 @@
+ALTER TABLE apiKeys ADD COLUMN dailyQuota INTEGER
+    CONSTRAINT api_keys_daily_quota_check CHECK (dailyQuota > 0);
+
+CREATE TABLE apiKeyUsage (
+    apiKeyId        INTEGER NOT NULL REFERENCES apiKeys (apiKeyId) ON DELETE CASCADE,
+    day             DATE NOT NULL,
+    requests        INTEGER NOT NULL,
+    PRIMARY KEY (apiKeyId, day)
+);
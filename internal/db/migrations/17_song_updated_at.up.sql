@@ -0,0 +1 @@
+ALTER TABLE songs ADD COLUMN updatedAt TIMESTAMPTZ NOT NULL DEFAULT now();
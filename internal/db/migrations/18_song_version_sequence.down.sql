@@ -0,0 +1,3 @@
+ALTER TABLE songs ALTER COLUMN version SET DEFAULT 1;
+ALTER TABLE songs ALTER COLUMN version TYPE INTEGER;
+DROP SEQUENCE songVersions;
@@ -0,0 +1,6 @@
+-- Versions come from one sequence, so a version is never handed out twice, not even
+-- to a song created again under the id of a deleted one, and ETags stay unique.
+CREATE SEQUENCE songVersions;
+ALTER TABLE songs ALTER COLUMN version TYPE BIGINT;
+ALTER TABLE songs ALTER COLUMN version SET DEFAULT nextval('songVersions');
+UPDATE songs SET version = nextval('songVersions');
@@ -0,0 +1,2 @@
+-- Playlists created before this have no owner, only library writers can change them.
+ALTER TABLE playlists ADD COLUMN ownerId INTEGER REFERENCES users (userId) ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP INDEX songs_group_name_key;
@@ -0,0 +1,24 @@
+-- Song names are unique within a group ignoring case, like group names. Existing
+-- duplicates would make the index fail with only one of them named, so list them all.
+-- The file runs as one implicit transaction and nothing of it is applied, but
+-- golang-migrate records version 20 as dirty. Rename the listed songs, then reset the
+-- version and restart the server:
+--   migrate -path internal/db/migrations -database "$CONNSTRMIGRATION" force 19
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(ids, '; ') INTO duplicates
+    FROM (
+        SELECT array_to_string(array_agg(songId ORDER BY songId), ', ') AS ids
+        FROM songs
+        GROUP BY groupId, lower(songName)
+        HAVING count(*) > 1
+    ) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'songs with the same name in a group, by songId: %', duplicates;
+    END IF;
+END;
+$$;
+
+CREATE UNIQUE INDEX songs_group_name_key ON songs (groupId, lower(songName));
@@ -0,0 +1 @@
+DROP INDEX groups_search_idx;
@@ -0,0 +1,2 @@
+-- Song search matches groups.search on its own, see searchQuery.
+CREATE INDEX groups_search_idx ON groups USING GIN (search);
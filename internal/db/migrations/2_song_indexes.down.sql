@@ -0,0 +1,4 @@
+DROP INDEX songs_link_idx;
+DROP INDEX songs_release_date_idx;
+DROP INDEX songs_name_idx;
+DROP INDEX songs_group_idx;
@@ -0,0 +1,4 @@
+CREATE INDEX songs_group_idx ON songs (groupName, songId);
+CREATE INDEX songs_name_idx ON songs (songName, songId);
+CREATE INDEX songs_release_date_idx ON songs (releaseDate, songId);
+CREATE INDEX songs_link_idx ON songs (songLink, songId);
@@ -0,0 +1,2 @@
+DROP INDEX songs_name_prefix_idx;
+DROP INDEX songs_group_prefix_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX songs_group_prefix_idx ON songs (groupName text_pattern_ops);
+CREATE INDEX songs_name_prefix_idx ON songs (songName text_pattern_ops);
@@ -0,0 +1,2 @@
+DROP INDEX songs_search_idx;
+ALTER TABLE songs DROP COLUMN search;
@@ -0,0 +1,7 @@
+ALTER TABLE songs ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(songName, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(groupName, '')), 'A') ||
+    setweight(to_tsvector('english', replace(coalesce(songText, ''), '\n', ' ')), 'B')
+) STORED;
+
+CREATE INDEX songs_search_idx ON songs USING GIN (search);
@@ -0,0 +1,2 @@
+DROP INDEX songs_name_trgm_idx;
+DROP INDEX songs_group_trgm_idx;
@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX songs_group_trgm_idx ON songs USING GIN (groupName gin_trgm_ops);
+CREATE INDEX songs_name_trgm_idx ON songs USING GIN (songName gin_trgm_ops);
@@ -0,0 +1,16 @@
+ALTER TABLE songs ADD COLUMN releaseDateText TEXT;
+
+UPDATE songs SET releaseDateText = CASE
+    WHEN releaseDate IS NULL THEN ''
+    WHEN releaseDate < '0001-01-01' THEN
+        to_char(releaseDate, CASE releaseDatePrecision WHEN 'day' THEN 'FMYYYY-MM-DD' WHEN 'month' THEN 'FMYYYY-MM' ELSE 'FMYYYY' END) || ' BCE'
+    ELSE
+        to_char(releaseDate, CASE releaseDatePrecision WHEN 'day' THEN 'YYYY-MM-DD' WHEN 'month' THEN 'YYYY-MM' ELSE 'YYYY' END)
+END;
+
+DROP INDEX songs_release_date_idx;
+ALTER TABLE songs DROP CONSTRAINT songs_release_date_precision_check;
+ALTER TABLE songs DROP COLUMN releaseDate, DROP COLUMN releaseDatePrecision;
+ALTER TABLE songs RENAME COLUMN releaseDateText TO releaseDate;
+
+CREATE INDEX songs_release_date_idx ON songs (releaseDate, songId);
@@ -0,0 +1,16 @@
+CREATE TABLE jobs (
+    jobId           BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    kind            TEXT NOT NULL,
+    songId          INTEGER NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'done', 'dead')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    maxAttempts     INTEGER NOT NULL,
+    runAt           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    lockedUntil     TIMESTAMPTZ,
+    lastError       TEXT,
+    createdAt       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updatedAt       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX jobs_queued_idx ON jobs (runAt, jobId) WHERE status = 'queued';
+CREATE INDEX jobs_running_idx ON jobs (lockedUntil) WHERE status = 'running';
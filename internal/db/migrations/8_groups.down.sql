@@ -0,0 +1,17 @@
+ALTER TABLE songs ADD COLUMN groupName TEXT;
+UPDATE songs SET groupName = groups.groupName FROM groups WHERE groups.groupId = songs.groupId;
+
+ALTER TABLE songs DROP COLUMN search;
+ALTER TABLE songs ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(songName, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(groupName, '')), 'A') ||
+    setweight(to_tsvector('english', replace(coalesce(songText, ''), '\n', ' ')), 'B')
+) STORED;
+CREATE INDEX songs_search_idx ON songs USING GIN (search);
+
+ALTER TABLE songs DROP COLUMN groupId;
+DROP TABLE groups;
+
+CREATE INDEX songs_group_idx ON songs (groupName, songId);
+CREATE INDEX songs_group_prefix_idx ON songs (groupName text_pattern_ops);
+CREATE INDEX songs_group_trgm_idx ON songs USING GIN (groupName gin_trgm_ops);
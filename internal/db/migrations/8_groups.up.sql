@@ -0,0 +1,36 @@
+CREATE TABLE groups (
+    groupId         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    groupName       TEXT NOT NULL,
+    search          tsvector GENERATED ALWAYS AS (setweight(to_tsvector('english', groupName), 'A')) STORED
+);
+
+CREATE UNIQUE INDEX groups_name_key ON groups (lower(groupName));
+CREATE INDEX groups_name_idx ON groups (groupName, groupId);
+CREATE INDEX groups_name_prefix_idx ON groups (groupName text_pattern_ops);
+CREATE INDEX groups_name_trgm_idx ON groups USING GIN (groupName gin_trgm_ops);
+
+-- Names that differ only in case or surrounding whitespace become one group,
+-- spelled the way its oldest song spells it.
+INSERT INTO groups (groupName)
+SELECT DISTINCT ON (lower(btrim(coalesce(groupName, '')))) btrim(coalesce(groupName, ''))
+FROM songs
+ORDER BY lower(btrim(coalesce(groupName, ''))), songId;
+
+ALTER TABLE songs ADD COLUMN groupId INTEGER REFERENCES groups (groupId);
+UPDATE songs SET groupId = groups.groupId
+FROM groups
+WHERE lower(groups.groupName) = lower(btrim(coalesce(songs.groupName, '')));
+ALTER TABLE songs ALTER COLUMN groupId SET NOT NULL;
+
+CREATE INDEX songs_group_id_idx ON songs (groupId, songId);
+
+-- The search column indexes the group name, so it is rebuilt without it and
+-- queries combine it with groups.search.
+ALTER TABLE songs DROP COLUMN search;
+ALTER TABLE songs DROP COLUMN groupName;
+ALTER TABLE songs ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(songName, '')), 'A') ||
+    setweight(to_tsvector('english', replace(coalesce(songText, ''), '\n', ' ')), 'B')
+) STORED;
+
+CREATE INDEX songs_search_idx ON songs USING GIN (search);
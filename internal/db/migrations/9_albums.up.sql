@@ -0,0 +1,26 @@
+CREATE TABLE albums (
+    albumId                 INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    groupId                 INTEGER NOT NULL REFERENCES groups (groupId),
+    title                   TEXT NOT NULL,
+    releaseDate             DATE,
+    releaseDatePrecision    TEXT,
+    coverLink               TEXT NOT NULL DEFAULT '',
+    CONSTRAINT albums_release_date_precision_check CHECK (
+        (releaseDate IS NULL AND releaseDatePrecision IS NULL) OR
+        (releaseDate IS NOT NULL AND releaseDatePrecision IN ('year', 'month', 'day'))
+    )
+);
+
+CREATE UNIQUE INDEX albums_group_title_key ON albums (groupId, lower(title));
+
+-- The position constraint is deferrable so a single UPDATE can shift track numbers.
+CREATE TABLE tracks (
+    albumId     INTEGER NOT NULL REFERENCES albums (albumId) ON DELETE CASCADE,
+    songId      INTEGER NOT NULL REFERENCES songs (songId) ON DELETE CASCADE,
+    disc        INTEGER NOT NULL CHECK (disc > 0),
+    number      INTEGER NOT NULL CHECK (number > 0),
+    PRIMARY KEY (albumId, songId),
+    CONSTRAINT tracks_position_key UNIQUE (albumId, disc, number) DEFERRABLE
+);
+
+CREATE INDEX tracks_song_idx ON tracks (songId);
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lynxbites/musiclib"
+)
+
+// PlaylistStore is the Postgres implementation of musiclib.PlaylistStore.
+type PlaylistStore struct {
+	db *DB
+}
+
+var _ musiclib.PlaylistStore = (*PlaylistStore)(nil)
+
+func NewPlaylistStore(db *DB) *PlaylistStore {
+	return &PlaylistStore{db: db}
+}
+
+// playlistColumns is the column list scanned by scanPlaylist.
+const playlistColumns = "playlistId, name, coalesce(ownerId, 0), (select count(*) from playlistEntries e where e.playlistId = playlists.playlistId), createdAt, updatedAt"
+
+func scanPlaylist(row pgx.Row) (musiclib.Playlist, error) {
+	var playlist musiclib.Playlist
+	err := row.Scan(&playlist.Id, &playlist.Name, &playlist.OwnerId, &playlist.SongCount, &playlist.CreatedAt, &playlist.UpdatedAt)
+	return playlist, err
+}
+
+// lockPlaylist locks the playlist row for the rest of the transaction and bumps its
+// updatedAt, every change to the entries goes through it so they run one at a time.
+func lockPlaylist(ctx context.Context, tx pgx.Tx, id int) error {
+	var locked int
+	err := tx.QueryRow(ctx, "update playlists set updatedAt = now() where playlistId = $1 returning playlistId", id).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return musiclib.ErrPlaylistNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock playlist %d: %w", id, err)
+	}
+	return nil
+}
+
+// playlistLength returns the number of entries of a locked playlist.
+func playlistLength(ctx context.Context, tx pgx.Tx, id int) (int, error) {
+	var length int
+	err := tx.QueryRow(ctx, "select count(*) from playlistEntries where playlistId = $1", id).Scan(&length)
+	if err != nil {
+		return 0, fmt.Errorf("count playlist entries: %w", err)
+	}
+	return length, nil
+}
+
+func (s *PlaylistStore) List(ctx context.Context, limit, offset int) ([]musiclib.Playlist, error) {
+	query := "select " + playlistColumns + " from playlists order by name, playlistId"
+	var args []any
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query playlists: %w", err)
+	}
+	defer rows.Close()
+
+	var playlists []musiclib.Playlist
+	for rows.Next() {
+		playlist, err := scanPlaylist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan playlist: %w", err)
+		}
+		playlists = append(playlists, playlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read playlists: %w", err)
+	}
+	return playlists, nil
+}
+
+func (s *PlaylistStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from playlists").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count playlists: %w", err)
+	}
+	return count, nil
+}
+
+func (s *PlaylistStore) Get(ctx context.Context, id int) (musiclib.Playlist, error) {
+	var playlist musiclib.Playlist
+	// Reading the playlist and its entries in one snapshot keeps a concurrent move
+	// from showing up half applied.
+	err := pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		var err error
+		playlist, err = scanPlaylist(tx.QueryRow(ctx, "select "+playlistColumns+" from playlists where playlistId = $1", id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrPlaylistNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("query playlist %d: %w", id, err)
+		}
+
+		rows, err := tx.Query(ctx, "select "+songColumns+", entryId, position, addedAt from playlistEntries join songs using (songId) join groups using (groupId) where playlistId = $1 order by position", id)
+		if err != nil {
+			return fmt.Errorf("query entries of playlist %d: %w", id, err)
+		}
+		defer rows.Close()
+
+		playlist.Entries = []musiclib.PlaylistEntry{}
+		for rows.Next() {
+			var entry musiclib.PlaylistEntry
+			entry.Song, err = scanSong(rows, &entry.Id, &entry.Position, &entry.AddedAt)
+			if err != nil {
+				return fmt.Errorf("scan playlist entry: %w", err)
+			}
+			playlist.Entries = append(playlist.Entries, entry)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("read playlist entries: %w", err)
+		}
+		return nil
+	})
+	return playlist, err
+}
+
+func (s *PlaylistStore) Create(ctx context.Context, name string, ownerId int) (musiclib.Playlist, error) {
+	playlist, err := scanPlaylist(s.db.QueryRow(ctx, "insert into playlists (name, ownerId) values ($1, nullif($2, 0)) returning "+playlistColumns, name, ownerId))
+	if err != nil {
+		return playlist, fmt.Errorf("insert playlist: %w", err)
+	}
+	return playlist, nil
+}
+
+func (s *PlaylistStore) Update(ctx context.Context, playlist musiclib.Playlist) error {
+	tag, err := s.db.Exec(ctx, "update playlists set name = $1, updatedAt = now() where playlistId = $2", playlist.Name, playlist.Id)
+	if err != nil {
+		return fmt.Errorf("update playlist %d: %w", playlist.Id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrPlaylistNotFound
+	}
+	return nil
+}
+
+func (s *PlaylistStore) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, "delete from playlists where playlistId = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete playlist %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrPlaylistNotFound
+	}
+	return nil
+}
+
+func (s *PlaylistStore) AddEntry(ctx context.Context, playlistId, songId, position int) (musiclib.PlaylistEntry, error) {
+	var entry musiclib.PlaylistEntry
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockPlaylist(ctx, tx, playlistId); err != nil {
+			return err
+		}
+		length, err := playlistLength(ctx, tx, playlistId)
+		if err != nil {
+			return err
+		}
+
+		entry.Position = length + 1
+		if position > 0 && position <= length {
+			entry.Position = position
+			_, err = tx.Exec(ctx, "update playlistEntries set position = position + 1 where playlistId = $1 and position >= $2", playlistId, position)
+			if err != nil {
+				return fmt.Errorf("shift playlist entries: %w", err)
+			}
+		}
+
+		err = tx.QueryRow(ctx, "insert into playlistEntries (playlistId, songId, position) values ($1, $2, $3) returning entryId, addedAt",
+			playlistId, songId, entry.Position).Scan(&entry.Id, &entry.AddedAt)
+		if isPgError(err, pgForeignKeyViolation) {
+			return musiclib.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("insert playlist entry: %w", err)
+		}
+
+		entry.Song, err = scanSong(tx.QueryRow(ctx, "select "+songColumns+songsFrom+" where songId = $1", songId))
+		if err != nil {
+			return fmt.Errorf("query song %d: %w", songId, err)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+func (s *PlaylistStore) MoveEntry(ctx context.Context, playlistId int, entryId int64, position int) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockPlaylist(ctx, tx, playlistId); err != nil {
+			return err
+		}
+
+		var from int
+		err := tx.QueryRow(ctx, "select position from playlistEntries where playlistId = $1 and entryId = $2", playlistId, entryId).Scan(&from)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrEntryNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("query playlist entry %d: %w", entryId, err)
+		}
+		length, err := playlistLength(ctx, tx, playlistId)
+		if err != nil {
+			return err
+		}
+		to := min(max(position, 1), length)
+		if to == from {
+			return nil
+		}
+
+		// The entries between the two positions shift by one towards the old position,
+		// done in one statement so the deferrable position constraint holds at its end.
+		_, err = tx.Exec(ctx, `update playlistEntries
+set position = case when entryId = $2 then $4 when $3 < $4 then position - 1 else position + 1 end
+where playlistId = $1 and position between least($3::integer, $4::integer) and greatest($3::integer, $4::integer)`,
+			playlistId, entryId, from, to)
+		if err != nil {
+			return fmt.Errorf("move playlist entry %d: %w", entryId, err)
+		}
+		return nil
+	})
+}
+
+func (s *PlaylistStore) RemoveEntry(ctx context.Context, playlistId int, entryId int64) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := lockPlaylist(ctx, tx, playlistId); err != nil {
+			return err
+		}
+
+		var position int
+		err := tx.QueryRow(ctx, "delete from playlistEntries where playlistId = $1 and entryId = $2 returning position", playlistId, entryId).Scan(&position)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrEntryNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("delete playlist entry %d: %w", entryId, err)
+		}
+
+		_, err = tx.Exec(ctx, "update playlistEntries set position = position - 1 where playlistId = $1 and position > $2", playlistId, position)
+		if err != nil {
+			return fmt.Errorf("shift playlist entries: %w", err)
+		}
+		return nil
+	})
+}
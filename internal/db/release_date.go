@@ -0,0 +1,47 @@
+package db
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lynxbites/musiclib"
+)
+
+// releaseDateSortExpr sorts unknown release dates after all known ones and keeps
+// keyset comparisons free of NULLs.
+const releaseDateSortExpr = "coalesce(releaseDate, 'infinity'::date)"
+
+// releaseDateColumns holds the releaseDate and releaseDatePrecision columns, the date
+// column stores the first day of the period and is NULL for unknown dates.
+type releaseDateColumns struct {
+	date      pgtype.Date
+	precision pgtype.Text
+}
+
+func newReleaseDateColumns(d musiclib.ReleaseDate) releaseDateColumns {
+	if d.IsZero() {
+		return releaseDateColumns{}
+	}
+	return releaseDateColumns{
+		date:      pgtype.Date{Time: d.Start(), Valid: true},
+		precision: pgtype.Text{String: string(d.Precision), Valid: true},
+	}
+}
+
+func (c releaseDateColumns) value() musiclib.ReleaseDate {
+	if !c.date.Valid || !c.precision.Valid {
+		return musiclib.ReleaseDate{}
+	}
+	return musiclib.ReleaseDateFromTime(c.date.Time, musiclib.DatePrecision(c.precision.String))
+}
+
+// releaseDateSortValue converts a release date sort value from a cursor into a
+// parameter comparable with releaseDateSortExpr.
+func releaseDateSortValue(value string) (pgtype.Date, error) {
+	d, err := musiclib.ParseReleaseDate(value)
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+	if d.IsZero() {
+		return pgtype.Date{InfinityModifier: pgtype.Infinity, Valid: true}, nil
+	}
+	return pgtype.Date{Time: d.Start(), Valid: true}, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// searchQuery matches the generated search column of a song or the one of its group,
+// each against the whole query so both GIN indexes can be used, and ranks songs by the
+// two combined. A query mixing group and song words matches neither. Lyrics keep
+// verses separated by a literal \n, the same separator getSong splits on, so verse
+// indexes are computed from string_to_array with empty verses removed.
+const searchQuery = `select s.songId, g.groupName, s.songName, s.releaseDate, s.releaseDatePrecision, s.songLink,
+	ts_rank_cd(s.search || g.search, q) as rank,
+	ts_headline('english', replace(s.songText, '\n', ' '), q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'),
+	coalesce((
+		select array_agg(v.i - 1 order by v.i)
+		from unnest(array_remove(string_to_array(s.songText, '\n'), '')) with ordinality as v(verse, i)
+		where to_tsvector('english', v.verse) @@ q
+	), '{}')
+from songs s join groups g using (groupId), websearch_to_tsquery('english', $1) q
+where s.search @@ q or g.search @@ q
+order by rank desc, s.songId`
+
+func (s *SongStore) Search(ctx context.Context, opts musiclib.SongSearchOptions) ([]musiclib.SongSearchResult, error) {
+	query := searchQuery
+	args := []any{opts.Query}
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search songs: %w", err)
+	}
+	defer rows.Close()
+
+	var results []musiclib.SongSearchResult
+	for rows.Next() {
+		var result musiclib.SongSearchResult
+		var releaseDate releaseDateColumns
+		err := rows.Scan(&result.Id, &result.Group, &result.Name, &releaseDate.date, &releaseDate.precision, &result.Link, &result.Rank, &result.Snippet, &result.Verses)
+		result.ReleaseDate = releaseDate.value()
+		if err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read search results: %w", err)
+	}
+	return results, nil
+}
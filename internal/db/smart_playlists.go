@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lynxbites/musiclib"
+)
+
+// SmartPlaylistStore is the Postgres implementation of musiclib.SmartPlaylistStore.
+// Rules are stored as a JSON array and validated by the caller.
+type SmartPlaylistStore struct {
+	db *DB
+}
+
+var _ musiclib.SmartPlaylistStore = (*SmartPlaylistStore)(nil)
+
+func NewSmartPlaylistStore(db *DB) *SmartPlaylistStore {
+	return &SmartPlaylistStore{db: db}
+}
+
+// smartPlaylistColumns is the column list scanned by scanSmartPlaylist.
+const smartPlaylistColumns = "smartPlaylistId, name, rules, createdAt, updatedAt"
+
+func scanSmartPlaylist(row pgx.Row) (musiclib.SmartPlaylist, error) {
+	var playlist musiclib.SmartPlaylist
+	err := row.Scan(&playlist.Id, &playlist.Name, &playlist.Rules, &playlist.CreatedAt, &playlist.UpdatedAt)
+	if playlist.Rules == nil {
+		playlist.Rules = musiclib.SongRules{}
+	}
+	return playlist, err
+}
+
+func (s *SmartPlaylistStore) List(ctx context.Context, limit, offset int) ([]musiclib.SmartPlaylist, error) {
+	query := "select " + smartPlaylistColumns + " from smartPlaylists order by name, smartPlaylistId"
+	var args []any
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query smart playlists: %w", err)
+	}
+	defer rows.Close()
+
+	var playlists []musiclib.SmartPlaylist
+	for rows.Next() {
+		playlist, err := scanSmartPlaylist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan smart playlist: %w", err)
+		}
+		playlists = append(playlists, playlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read smart playlists: %w", err)
+	}
+	return playlists, nil
+}
+
+func (s *SmartPlaylistStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from smartPlaylists").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count smart playlists: %w", err)
+	}
+	return count, nil
+}
+
+func (s *SmartPlaylistStore) Get(ctx context.Context, id int) (musiclib.SmartPlaylist, error) {
+	playlist, err := scanSmartPlaylist(s.db.QueryRow(ctx, "select "+smartPlaylistColumns+" from smartPlaylists where smartPlaylistId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return playlist, musiclib.ErrSmartPlaylistNotFound
+	}
+	if err != nil {
+		return playlist, fmt.Errorf("query smart playlist %d: %w", id, err)
+	}
+	return playlist, nil
+}
+
+func (s *SmartPlaylistStore) Create(ctx context.Context, playlist musiclib.SmartPlaylist) (musiclib.SmartPlaylist, error) {
+	if playlist.Rules == nil {
+		playlist.Rules = musiclib.SongRules{}
+	}
+	playlist, err := scanSmartPlaylist(s.db.QueryRow(ctx, "insert into smartPlaylists (name, rules) values ($1, $2) returning "+smartPlaylistColumns,
+		playlist.Name, playlist.Rules))
+	if err != nil {
+		return playlist, fmt.Errorf("insert smart playlist: %w", err)
+	}
+	return playlist, nil
+}
+
+func (s *SmartPlaylistStore) Update(ctx context.Context, playlist musiclib.SmartPlaylist) error {
+	if playlist.Rules == nil {
+		playlist.Rules = musiclib.SongRules{}
+	}
+	tag, err := s.db.Exec(ctx, "update smartPlaylists set name = $1, rules = $2, updatedAt = now() where smartPlaylistId = $3",
+		playlist.Name, playlist.Rules, playlist.Id)
+	if err != nil {
+		return fmt.Errorf("update smart playlist %d: %w", playlist.Id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrSmartPlaylistNotFound
+	}
+	return nil
+}
+
+func (s *SmartPlaylistStore) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, "delete from smartPlaylists where smartPlaylistId = $1", id)
+	if err != nil {
+		return fmt.Errorf("delete smart playlist %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return musiclib.ErrSmartPlaylistNotFound
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lynxbites/musiclib"
+)
+
+// SongStore is the Postgres implementation of musiclib.SongStore.
+type SongStore struct {
+	db *DB
+}
+
+var _ musiclib.SongStore = (*SongStore)(nil)
+
+func NewSongStore(db *DB) *SongStore {
+	return &SongStore{db: db}
+}
+
+// Health reports whether the underlying pool can reach the database.
+func (s *SongStore) Health(ctx context.Context) error {
+	return s.db.Health(ctx)
+}
+
+// sortColumns maps API sort keys to columns, only these are ever put into SQL.
+var sortColumns = map[string]string{
+	musiclib.SortById:    "songId",
+	musiclib.SortByGroup: "groupName",
+	musiclib.SortByName:  "songName",
+	musiclib.SortByDate:  releaseDateSortExpr,
+	musiclib.SortByText:  "songText",
+	musiclib.SortByLink:  "songLink",
+}
+
+// songsFrom joins songs with their groups, so filters and sorts can use groupName.
+const songsFrom = " from songs join groups using (groupId)"
+
+// songColumns is the column list scanned by scanSong.
+const songColumns = "songId, groupId, groupName, songName, releaseDate, releaseDatePrecision, songText, songLink, version, updatedAt"
+
+// scanSong scans songColumns, extra destinations receive the columns selected after them.
+func scanSong(row pgx.Row, extra ...any) (musiclib.Song, error) {
+	var song musiclib.Song
+	var releaseDate releaseDateColumns
+	dest := []any{&song.Id, &song.GroupId, &song.Group, &song.Name, &releaseDate.date, &releaseDate.precision, &song.Text, &song.Link, &song.Version, &song.UpdatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	song.ReleaseDate = releaseDate.value()
+	return song, err
+}
+
+// linkHostExpr extracts the lower-cased host part of songLink.
+const linkHostExpr = `lower(substring(songLink from '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^@/?#]*@)?([^/:?#]+)'))`
+
+// whereBuilder collects AND-ed conditions, each "?" in a condition is bound to its argument.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (b *whereBuilder) add(cond string, arg any) {
+	b.args = append(b.args, arg)
+	b.conds = append(b.conds, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(b.args))))
+}
+
+func (b *whereBuilder) sql() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return " where " + strings.Join(b.conds, " and ")
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func songFilterWhere(f musiclib.SongFilter) *whereBuilder {
+	b := &whereBuilder{}
+	if f.GroupId != 0 {
+		b.add("groupId = ?", f.GroupId)
+	}
+	if f.Group != "" {
+		b.add("lower(groupName) = lower(?)", f.Group)
+	}
+	if f.GroupPrefix != "" {
+		b.add("groupName like ?", escapeLike(f.GroupPrefix)+"%")
+	}
+	if f.GroupContains != "" {
+		b.add("groupName ilike ?", "%"+escapeLike(f.GroupContains)+"%")
+	}
+	if f.GroupFuzzy != "" {
+		b.add("? <% groupName", f.GroupFuzzy)
+	}
+	if f.GroupIn != nil {
+		groups := make([]string, len(f.GroupIn))
+		for i, group := range f.GroupIn {
+			groups[i] = strings.ToLower(group)
+		}
+		b.add("lower(groupName) = any(?)", groups)
+	}
+	if f.Name != "" {
+		b.add("songName = ?", f.Name)
+	}
+	if f.NameIn != nil {
+		b.add("songName = any(?)", f.NameIn)
+	}
+	if f.NamePrefix != "" {
+		b.add("songName like ?", escapeLike(f.NamePrefix)+"%")
+	}
+	if f.NameContains != "" {
+		b.add("songName ilike ?", "%"+escapeLike(f.NameContains)+"%")
+	}
+	if f.NameFuzzy != "" {
+		b.add("? <% songName", f.NameFuzzy)
+	}
+	if !f.ReleaseDateFrom.IsZero() {
+		b.add("releaseDate >= ?", pgtype.Date{Time: f.ReleaseDateFrom.Start(), Valid: true})
+	}
+	if !f.ReleaseDateTo.IsZero() {
+		b.add("releaseDate <= ?", pgtype.Date{Time: f.ReleaseDateTo.End(), Valid: true})
+	}
+	if f.LinkHost != "" {
+		b.add("("+linkHostExpr+" = ? or right("+linkHostExpr+", length(?) + 1) = '.' || ?)", strings.ToLower(f.LinkHost))
+	}
+	if f.TextContains != "" {
+		b.add("songText ilike ?", "%"+escapeLike(f.TextContains)+"%")
+	}
+	return b
+}
+
+func songOrderBy(sorts []musiclib.SongSort) string {
+	var keys []string
+	for _, sort := range musiclib.NormalizeSort(sorts) {
+		column := sortColumns[sort.Field]
+		if sort.Desc {
+			column += " desc"
+		}
+		keys = append(keys, column)
+	}
+	return " order by " + strings.Join(keys, ", ")
+}
+
+// addKeyset restricts the list to rows ordered after the given sort key values, e.g.
+// (a > $1) or (a = $1 and b < $2) or (a = $1 and b = $2 and songId > $3).
+func (b *whereBuilder) addKeyset(sorts []musiclib.SongSort, after []string) error {
+	keys := musiclib.NormalizeSort(sorts)
+	if len(after) != len(keys) {
+		return errors.New("cursor does not match the sort keys")
+	}
+
+	var placeholders []string
+	for i, value := range after {
+		var arg any = value
+		if keys[i].Field == musiclib.SortByDate {
+			date, err := releaseDateSortValue(value)
+			if err != nil {
+				return err
+			}
+			arg = date
+		}
+		b.args = append(b.args, arg)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(b.args)))
+	}
+
+	var alternatives []string
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, sortColumns[keys[j].Field]+" = "+placeholders[j])
+		}
+		op := " > "
+		if key.Desc {
+			op = " < "
+		}
+		parts = append(parts, sortColumns[key.Field]+op+placeholders[i])
+		alternatives = append(alternatives, "("+strings.Join(parts, " and ")+")")
+	}
+	b.conds = append(b.conds, "("+strings.Join(alternatives, " or ")+")")
+	return nil
+}
+
+func (s *SongStore) List(ctx context.Context, opts musiclib.SongListOptions) ([]musiclib.Song, error) {
+	where := songFilterWhere(opts.Filter)
+	if len(opts.After) > 0 {
+		if err := where.addKeyset(opts.Sort, opts.After); err != nil {
+			return nil, fmt.Errorf("apply cursor: %w", err)
+		}
+	}
+	query := "select " + songColumns + songsFrom + where.sql() + songOrderBy(opts.Sort)
+
+	args := where.args
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query songs: %w", err)
+	}
+	defer rows.Close()
+
+	var songs []musiclib.Song
+	for rows.Next() {
+		song, err := scanSong(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan song: %w", err)
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read songs: %w", err)
+	}
+	return songs, nil
+}
+
+func (s *SongStore) Count(ctx context.Context, filter musiclib.SongFilter) (int, error) {
+	where := songFilterWhere(filter)
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*)"+songsFrom+where.sql(), where.args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count songs: %w", err)
+	}
+	return count, nil
+}
+
+func (s *SongStore) Get(ctx context.Context, id int) (musiclib.Song, error) {
+	song, err := scanSong(s.db.QueryRow(ctx, "select "+songColumns+songsFrom+" where songId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return song, musiclib.ErrNotFound
+	}
+	if err != nil {
+		return song, fmt.Errorf("query song %d: %w", id, err)
+	}
+	return song, nil
+}
+
+// songNameKey is the unique index on the group and the lower-cased name of a song.
+const songNameKey = "songs_group_name_key"
+
+func (s *SongStore) Create(ctx context.Context, song musiclib.Song) (musiclib.Song, error) {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, resolveGroupQuery, song.Group).Scan(&song.GroupId)
+		if err != nil {
+			return fmt.Errorf("resolve group: %w", err)
+		}
+
+		releaseDate := newReleaseDateColumns(song.ReleaseDate)
+		err = tx.QueryRow(ctx, `insert into songs (groupId, songName, releaseDate, releaseDatePrecision, songText, songLink) values ($1,$2,$3,$4,$5,$6) returning songId, version, updatedAt`,
+			song.GroupId, song.Name, releaseDate.date, releaseDate.precision, song.Text, song.Link).Scan(&song.Id, &song.Version, &song.UpdatedAt)
+		if isPgConstraintError(err, pgUniqueViolation, songNameKey) {
+			return musiclib.ErrConflict
+		}
+		if err != nil {
+			return fmt.Errorf("insert song: %w", err)
+		}
+		return nil
+	})
+	return song, err
+}
+
+// Update moves the song to the group named song.Group, creating the group when missing.
+func (s *SongStore) Update(ctx context.Context, song musiclib.Song) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var groupId int
+		err := tx.QueryRow(ctx, resolveGroupQuery, song.Group).Scan(&groupId)
+		if err != nil {
+			return fmt.Errorf("resolve group: %w", err)
+		}
+
+		releaseDate := newReleaseDateColumns(song.ReleaseDate)
+		tag, err := tx.Exec(ctx, `update songs set groupId = $1, songName = $2, releaseDate = $3, releaseDatePrecision = $4, songText = $5, songLink = $6, version = nextval('songVersions'), updatedAt = now() where songId = $7`,
+			groupId, song.Name, releaseDate.date, releaseDate.precision, song.Text, song.Link, song.Id)
+		if isPgConstraintError(err, pgUniqueViolation, songNameKey) {
+			return musiclib.ErrConflict
+		}
+		if err != nil {
+			return fmt.Errorf("update song %s: %w", song.Id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return musiclib.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *SongStore) Put(ctx context.Context, song musiclib.Song, version int, create bool) (musiclib.Song, bool, error) {
+	var created bool
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		exists, err := replaceSong(ctx, tx, &song, version)
+		if err != nil || exists {
+			return err
+		}
+		if !create {
+			return musiclib.ErrNotFound
+		}
+		if version > 0 {
+			return musiclib.ErrVersionMismatch
+		}
+
+		releaseDate := newReleaseDateColumns(song.ReleaseDate)
+		err = tx.QueryRow(ctx, `insert into songs (songId, groupId, songName, releaseDate, releaseDatePrecision, songText, songLink) overriding system value values ($1,$2,$3,$4,$5,$6,$7) returning version, updatedAt`,
+			song.Id, song.GroupId, song.Name, releaseDate.date, releaseDate.precision, song.Text, song.Link).Scan(&song.Version, &song.UpdatedAt)
+		if isPgConstraintError(err, pgUniqueViolation, songNameKey) {
+			return musiclib.ErrConflict
+		}
+		if isPgError(err, pgUniqueViolation) {
+			// Another request created the song since replaceSong looked.
+			return musiclib.ErrVersionMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("insert song %s: %w", song.Id, err)
+		}
+		// The identity would hand out the id again once it gets there. It only moves
+		// forward, ids above the current maximum may belong to deleted songs.
+		_, err = tx.Exec(ctx, `select setval(seq::regclass, greatest((select max(songId) from songs), pg_sequence_last_value(seq::regclass)))
+from pg_get_serial_sequence('songs', 'songid') as seq`)
+		if err != nil {
+			return fmt.Errorf("advance song ids: %w", err)
+		}
+		created = true
+		return nil
+	})
+	return song, created, err
+}
+
+// Patch runs the read, patch and write in one transaction and keeps the row locked in
+// between, so concurrent patches of the song apply one after another.
+func (s *SongStore) Patch(ctx context.Context, id int, version int, patch func(musiclib.Song) (musiclib.Song, error)) (musiclib.Song, error) {
+	var song musiclib.Song
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		current, err := scanSong(tx.QueryRow(ctx, "select "+songColumns+songsFrom+" where songId = $1 for update of songs", id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("query song %d: %w", id, err)
+		}
+		if version != musiclib.AnyVersion && version != current.Version {
+			return musiclib.ErrVersionMismatch
+		}
+
+		song, err = patch(current)
+		if err != nil {
+			return err
+		}
+		song.Id = current.Id
+		exists, err := replaceSong(ctx, tx, &song, musiclib.AnyVersion)
+		if err == nil && !exists {
+			return musiclib.ErrNotFound
+		}
+		return err
+	})
+	return song, err
+}
+
+// replaceSong overwrites the row of song.Id when it is at version and reports whether
+// the song exists. It resolves song.GroupId from song.Group, sets song.Version to the
+// new version and returns ErrConflict when another song has the same group and name.
+func replaceSong(ctx context.Context, tx pgx.Tx, song *musiclib.Song, version int) (bool, error) {
+	err := tx.QueryRow(ctx, resolveGroupQuery, song.Group).Scan(&song.GroupId)
+	if err != nil {
+		return false, fmt.Errorf("resolve group: %w", err)
+	}
+
+	releaseDate := newReleaseDateColumns(song.ReleaseDate)
+	err = tx.QueryRow(ctx, `update songs set groupId = $1, songName = $2, releaseDate = $3, releaseDatePrecision = $4, songText = $5, songLink = $6, version = nextval('songVersions'), updatedAt = now() where songId = $7 and ($8 = 0 or version = $8) returning version, updatedAt`,
+		song.GroupId, song.Name, releaseDate.date, releaseDate.precision, song.Text, song.Link, song.Id, version).Scan(&song.Version, &song.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, songVersionError(ctx, tx, song.Id)
+	}
+	if isPgConstraintError(err, pgUniqueViolation, songNameKey) {
+		return false, musiclib.ErrConflict
+	}
+	if err != nil {
+		return false, fmt.Errorf("update song %s: %w", song.Id, err)
+	}
+	return true, nil
+}
+
+// songVersionError tells why a write guarded by a version matched no row: the song
+// exists at another version, or it does not exist and the error is nil.
+func songVersionError(ctx context.Context, tx pgx.Tx, id any) error {
+	var exists bool
+	err := tx.QueryRow(ctx, "select exists(select 1 from songs where songId = $1)", id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check song %v exists: %w", id, err)
+	}
+	if exists {
+		return musiclib.ErrVersionMismatch
+	}
+	return nil
+}
+
+// Delete removes the song, the album tracks and playlist entries after it move up so
+// their numbering stays without gaps. The albums and playlists are locked the way
+// their stores lock them, so this cannot interleave with a reorder.
+func (s *SongStore) Delete(ctx context.Context, id int, version int) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var albumIds, playlistIds []int
+		err := tx.QueryRow(ctx, `select coalesce(array_agg(albumId), '{}') from (
+	select albumId from albums where albumId in (select albumId from tracks where songId = $1) order by albumId for update
+) a`, id).Scan(&albumIds)
+		if err != nil {
+			return fmt.Errorf("lock albums of song %d: %w", id, err)
+		}
+		err = tx.QueryRow(ctx, `select coalesce(array_agg(playlistId), '{}') from (
+	select playlistId from playlists where playlistId in (select playlistId from playlistEntries where songId = $1) order by playlistId for update
+) p`, id).Scan(&playlistIds)
+		if err != nil {
+			return fmt.Errorf("lock playlists of song %d: %w", id, err)
+		}
+
+		tag, err := tx.Exec(ctx, "delete from songs where songId = $1 and ($2 = 0 or version = $2)", id, version)
+		if err != nil {
+			return fmt.Errorf("delete song %d: %w", id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			if err := songVersionError(ctx, tx, id); err != nil {
+				return err
+			}
+			return musiclib.ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, `update tracks set number = r.number
+from (select albumId, songId, row_number() over (partition by albumId, disc order by number) as number from tracks where albumId = any($1)) r
+where tracks.albumId = r.albumId and tracks.songId = r.songId and tracks.number <> r.number`, albumIds)
+		if err != nil {
+			return fmt.Errorf("renumber tracks: %w", err)
+		}
+		_, err = tx.Exec(ctx, `update playlistEntries set position = r.position
+from (select entryId, row_number() over (partition by playlistId order by position) as position from playlistEntries where playlistId = any($1)) r
+where playlistEntries.entryId = r.entryId and playlistEntries.position <> r.position`, playlistIds)
+		if err != nil {
+			return fmt.Errorf("renumber playlist entries: %w", err)
+		}
+		_, err = tx.Exec(ctx, "update playlists set updatedAt = now() where playlistId = any($1)", playlistIds)
+		if err != nil {
+			return fmt.Errorf("touch playlists: %w", err)
+		}
+		return nil
+	})
+}
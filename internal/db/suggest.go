@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// Suggestions score a prefix match as 1 and anything else by pg_trgm word similarity,
+// the <% operator keeps the trigram indexes usable.
+const suggestGroupsQuery = `select groupName,
+	greatest(word_similarity($1, groupName), case when groupName ilike $2 then 1 else 0 end) as score
+from groups
+where groupName ilike $2 or $1 <% groupName
+order by score desc, groupName
+limit $3`
+
+const suggestSongsQuery = `select songId, groupName, songName,
+	greatest(word_similarity($1, songName), case when songName ilike $2 then 1 else 0 end) as score
+from songs join groups using (groupId)
+where songName ilike $2 or $1 <% songName
+order by score desc, songName, songId
+limit $3`
+
+func (s *SongStore) Suggest(ctx context.Context, prefix string, limit int) (musiclib.Suggestions, error) {
+	suggestions := musiclib.Suggestions{
+		Groups: []musiclib.GroupSuggestion{},
+		Songs:  []musiclib.SongSuggestion{},
+	}
+	pattern := escapeLike(prefix) + "%"
+
+	rows, err := s.db.Query(ctx, suggestGroupsQuery, prefix, pattern, limit)
+	if err != nil {
+		return suggestions, fmt.Errorf("suggest groups: %w", err)
+	}
+	for rows.Next() {
+		var group musiclib.GroupSuggestion
+		if err := rows.Scan(&group.Group, &group.Score); err != nil {
+			rows.Close()
+			return suggestions, fmt.Errorf("scan group suggestion: %w", err)
+		}
+		suggestions.Groups = append(suggestions.Groups, group)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return suggestions, fmt.Errorf("read group suggestions: %w", err)
+	}
+
+	rows, err = s.db.Query(ctx, suggestSongsQuery, prefix, pattern, limit)
+	if err != nil {
+		return suggestions, fmt.Errorf("suggest songs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var song musiclib.SongSuggestion
+		if err := rows.Scan(&song.Id, &song.Group, &song.Name, &song.Score); err != nil {
+			return suggestions, fmt.Errorf("scan song suggestion: %w", err)
+		}
+		suggestions.Songs = append(suggestions.Songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return suggestions, fmt.Errorf("read song suggestions: %w", err)
+	}
+	return suggestions, nil
+}
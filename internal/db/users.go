@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lynxbites/musiclib"
+)
+
+// UserStore is the Postgres implementation of musiclib.UserStore.
+type UserStore struct {
+	db *DB
+}
+
+var _ musiclib.UserStore = (*UserStore)(nil)
+
+func NewUserStore(db *DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// userColumns is the column list scanned by scanUser.
+const userColumns = "userId, username, role, passwordHash, createdAt"
+
+func scanUser(row pgx.Row) (musiclib.User, error) {
+	var user musiclib.User
+	err := row.Scan(&user.Id, &user.Username, &user.Role, &user.PasswordHash, &user.CreatedAt)
+	return user, err
+}
+
+func (s *UserStore) List(ctx context.Context, limit, offset int) ([]musiclib.User, error) {
+	query := "select " + userColumns + " from users order by userId"
+	var args []any
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" limit $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" offset $%d", len(args))
+	}
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []musiclib.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read users: %w", err)
+	}
+	return users, nil
+}
+
+func (s *UserStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, "select count(*) from users").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count users: %w", err)
+	}
+	return count, nil
+}
+
+func (s *UserStore) Create(ctx context.Context, user musiclib.User) (musiclib.User, error) {
+	created, err := scanUser(s.db.QueryRow(ctx, "insert into users (username, role, passwordHash) values ($1, $2, $3) returning "+userColumns,
+		user.Username, user.Role, user.PasswordHash))
+	if isPgError(err, pgUniqueViolation) {
+		return user, musiclib.ErrUserConflict
+	}
+	if err != nil {
+		return user, fmt.Errorf("insert user: %w", err)
+	}
+	return created, nil
+}
+
+func (s *UserStore) Get(ctx context.Context, id int) (musiclib.User, error) {
+	user, err := scanUser(s.db.QueryRow(ctx, "select "+userColumns+" from users where userId = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, musiclib.ErrUserNotFound
+	}
+	if err != nil {
+		return user, fmt.Errorf("query user %d: %w", id, err)
+	}
+	return user, nil
+}
+
+func (s *UserStore) GetByName(ctx context.Context, username string) (musiclib.User, error) {
+	user, err := scanUser(s.db.QueryRow(ctx, "select "+userColumns+" from users where lower(username) = lower($1)", username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, musiclib.ErrUserNotFound
+	}
+	if err != nil {
+		return user, fmt.Errorf("query user %q: %w", username, err)
+	}
+	return user, nil
+}
+
+// SetRole locks the admin rows first, so two admins demoting each other cannot
+// both succeed.
+func (s *UserStore) SetRole(ctx context.Context, id int, role musiclib.Role) (musiclib.User, error) {
+	var user musiclib.User
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, "select userId from users where role = $1 order by userId for update", musiclib.RoleAdmin)
+		if err != nil {
+			return fmt.Errorf("lock admins: %w", err)
+		}
+		var admins []int
+		for rows.Next() {
+			var adminId int
+			if err := rows.Scan(&adminId); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan admin: %w", err)
+			}
+			admins = append(admins, adminId)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("lock admins: %w", err)
+		}
+		if role != musiclib.RoleAdmin && len(admins) == 1 && admins[0] == id {
+			return musiclib.ErrLastAdmin
+		}
+
+		user, err = scanUser(tx.QueryRow(ctx, "update users set role = $2 where userId = $1 returning "+userColumns, id, role))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return musiclib.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("update user %d: %w", id, err)
+		}
+		return nil
+	})
+	return user, err
+}
+
+// SaveRefreshToken also drops expired tokens, so the table does not grow without bound.
+func (s *UserStore) SaveRefreshToken(ctx context.Context, userId int, tokenHash []byte, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx, "delete from refreshTokens where expiresAt < now()")
+	if err != nil {
+		return fmt.Errorf("delete expired refresh tokens: %w", err)
+	}
+	_, err = s.db.Exec(ctx, "insert into refreshTokens (tokenHash, userId, expiresAt) values ($1, $2, $3)", tokenHash, userId, expiresAt)
+	if err != nil {
+		return fmt.Errorf("insert refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *UserStore) UseRefreshToken(ctx context.Context, tokenHash []byte) (int, error) {
+	var userId int
+	err := s.db.QueryRow(ctx, "delete from refreshTokens where tokenHash = $1 and expiresAt > now() returning userId", tokenHash).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, musiclib.ErrTokenInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("use refresh token: %w", err)
+	}
+	return userId, nil
+}
+
+func (s *UserStore) RevokeRefreshToken(ctx context.Context, tokenHash []byte) error {
+	_, err := s.db.Exec(ctx, "delete from refreshTokens where tokenHash = $1", tokenHash)
+	if err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package httpcache sets the caching headers of API responses and evaluates the
+// If-None-Match and If-Modified-Since conditions of GET requests.
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Config struct {
+	// Default is the Cache-Control of routes without an entry in Routes, empty sends none.
+	Default string
+	// Routes maps "METHOD /pattern" to the Cache-Control of that route, patterns are the
+	// chi route patterns without a trailing slash, e.g. "GET /api/v1/songs/{songId}".
+	Routes map[string]string
+}
+
+// ConfigFromEnv reads CACHECONTROL and CACHECONTROLROUTES. CACHECONTROL is the default
+// Cache-Control value, CACHECONTROLROUTES is a semicolon separated list of
+// "METHOD /pattern=value" entries, e.g. "GET /api/v1/songs=no-cache;GET /api/v1/songs/{songId}=private, max-age=60".
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Default: strings.TrimSpace(os.Getenv("CACHECONTROL")), Routes: make(map[string]string)}
+
+	if v := os.Getenv("CACHECONTROLROUTES"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			route, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+			method, pattern, ok := strings.Cut(strings.TrimSpace(route), " ")
+			if !found || !ok || strings.TrimSpace(value) == "" {
+				return cfg, fmt.Errorf("parse CACHECONTROLROUTES: invalid entry %q", entry)
+			}
+			cfg.Routes[routeKey(method, pattern)] = strings.TrimSpace(value)
+		}
+	}
+	return cfg, nil
+}
+
+func routeKey(method, pattern string) string {
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return strings.ToUpper(method) + " " + pattern
+}
+
+// CacheControl returns the Cache-Control value of the route that matched r.
+func (c Config) CacheControl(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if value, ok := c.Routes[routeKey(r.Method, rctx.RoutePattern())]; ok {
+			return value
+		}
+	}
+	return c.Default
+}
+
+// SetHeaders sets ETag, Last-Modified and Cache-Control, empty or zero values are left out.
+// Last-Modified has a resolution of seconds.
+func SetHeaders(w http.ResponseWriter, etag string, lastModified time.Time, cacheControl string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// NotModified reports whether a GET or HEAD request can be answered with 304 because
+// the client has the representation with etag and lastModified. If-None-Match is
+// compared weakly and, when present, If-Modified-Since is ignored as RFC 9110 requires.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && matchesAny(ifNoneMatch, etag)
+	}
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// matchesAny reports whether the If-None-Match list has "*" or a tag weakly equal to etag.
+func matchesAny(list, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
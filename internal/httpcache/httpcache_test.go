@@ -0,0 +1,106 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 12, 12, 10, 0, 0, 500_000_000, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	at := modified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		etag    string
+		want    bool
+	}{
+		{"no conditions", "GET", nil, `"7"`, false},
+		{"matching tag", "GET", map[string]string{"If-None-Match": `"7"`}, `"7"`, true},
+		{"tag in list", "HEAD", map[string]string{"If-None-Match": `"6", "7"`}, `"7"`, true},
+		{"weak tag", "GET", map[string]string{"If-None-Match": `W/"7"`}, `"7"`, true},
+		{"star", "GET", map[string]string{"If-None-Match": "*"}, `"7"`, true},
+		{"other tag", "GET", map[string]string{"If-None-Match": `"6"`}, `"7"`, false},
+		{"not a read", "PUT", map[string]string{"If-None-Match": `"7"`}, `"7"`, false},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": before}, `"7"`, false},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": at}, `"7"`, true},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, `"7"`, false},
+		// If-None-Match wins over If-Modified-Since.
+		{"tag before date", "GET", map[string]string{"If-None-Match": `"6"`, "If-Modified-Since": at}, `"7"`, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/v1/songs/1", nil)
+		for name, value := range tt.headers {
+			r.Header.Set(name, value)
+		}
+		if got := NotModified(r, tt.etag, modified); got != tt.want {
+			t.Errorf("%s: NotModified = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/api/v1/songs/", nil)
+	r.Header.Set("If-Modified-Since", at)
+	if NotModified(r, `"7"`, time.Time{}) {
+		t.Error("NotModified without Last-Modified = true, want false")
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetHeaders(w, `"7"`, time.Date(2024, 12, 12, 10, 0, 0, 0, time.FixedZone("MSK", 3*60*60)), "no-cache")
+	want := map[string]string{
+		"ETag":          `"7"`,
+		"Last-Modified": "Thu, 12 Dec 2024 07:00:00 GMT",
+		"Cache-Control": "no-cache",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	SetHeaders(w, "", time.Time{}, "")
+	if len(w.Header()) != 0 {
+		t.Errorf("headers = %v, want none", w.Header())
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("CACHECONTROL", " no-cache ")
+	t.Setenv("CACHECONTROLROUTES", "get /api/v1/songs/=no-store; GET /api/v1/songs/{songId}=private, max-age=60;")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv: %v", err)
+	}
+
+	router := chi.NewRouter()
+	var got string
+	handler := func(w http.ResponseWriter, r *http.Request) { got = cfg.CacheControl(r) }
+	router.Get("/api/v1/songs/", handler)
+	router.Get("/api/v1/songs/{songId}", handler)
+	router.Get("/api/v1/groups/", handler)
+	for target, want := range map[string]string{
+		"/api/v1/songs/":  "no-store",
+		"/api/v1/songs/1": "private, max-age=60",
+		"/api/v1/groups/": "no-cache",
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+		if got != want {
+			t.Errorf("Cache-Control of %s = %q, want %q", target, got, want)
+		}
+	}
+
+	for _, routes := range []string{"GET=no-cache", "GET /api/v1/songs", "GET /api/v1/songs= "} {
+		t.Setenv("CACHECONTROLROUTES", routes)
+		if _, err := ConfigFromEnv(); err == nil {
+			t.Errorf("ConfigFromEnv(%q) = nil error, want one", routes)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/musicinfo"
+)
+
+// EnrichSong returns the handler of JobKindEnrichSong: empty release date, lyrics and link
+// of the song are filled in from the info source. Deleted songs are skipped.
+func EnrichSong(songs musiclib.SongStore, info musiclib.SongInfoSource) Handler {
+	return func(ctx context.Context, job musiclib.Job) error {
+		song, err := songs.Get(ctx, job.SongId)
+		if errors.Is(err, musiclib.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !song.ReleaseDate.IsZero() && song.Text != "" && song.Link != "" {
+			return nil
+		}
+
+		detail, err := info.SongInfo(ctx, song.Group, song.Name)
+		if errors.Is(err, musicinfo.ErrNotFound) || errors.Is(err, musicinfo.ErrRejected) {
+			return Permanent(err)
+		}
+		if err != nil {
+			return fmt.Errorf("get song info: %w", err)
+		}
+		if invalid := detail.Sanitize(); len(invalid) > 0 {
+			log.Warn("Dropped invalid song info", "songId", job.SongId, "err", invalid)
+		}
+
+		// The song may have been edited during the lookup, only fields still empty are filled.
+		_, err = songs.Patch(ctx, job.SongId, musiclib.AnyVersion, func(song musiclib.Song) (musiclib.Song, error) {
+			if song.ReleaseDate.IsZero() {
+				song.ReleaseDate = detail.ReleaseDate
+			}
+			if song.Text == "" {
+				song.Text = detail.Text
+			}
+			if song.Link == "" {
+				song.Link = detail.Link
+			}
+			return song, nil
+		})
+		if errors.Is(err, musiclib.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,188 @@
+// Package jobs runs background jobs from a musiclib.JobQueue.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+)
+
+type Config struct {
+	// Workers is the number of jobs processed concurrently.
+	Workers int
+	// MaxAttempts is how many times a job runs before it becomes dead.
+	MaxAttempts int
+	// PollInterval is the wait between checks of an empty queue.
+	PollInterval time.Duration
+	// Backoff is the delay before the first retry, it doubles with each attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout limits a single run of a job, the job lease is slightly longer.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv reads JOBWORKERS, JOBMAXATTEMPTS, JOBPOLLINTERVAL, JOBBACKOFF,
+// JOBMAXBACKOFF and JOBTIMEOUT, unset values keep their defaults.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Workers:      4,
+		MaxAttempts:  5,
+		PollInterval: time.Second,
+		Backoff:      time.Second,
+		MaxBackoff:   10 * time.Minute,
+		Timeout:      30 * time.Second,
+	}
+	ints := map[string]*int{
+		"JOBWORKERS":     &cfg.Workers,
+		"JOBMAXATTEMPTS": &cfg.MaxAttempts,
+	}
+	for key, dst := range ints {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("parse %s: invalid value %q", key, v)
+		}
+		*dst = n
+	}
+	durations := map[string]*time.Duration{
+		"JOBPOLLINTERVAL": &cfg.PollInterval,
+		"JOBBACKOFF":      &cfg.Backoff,
+		"JOBMAXBACKOFF":   &cfg.MaxBackoff,
+		"JOBTIMEOUT":      &cfg.Timeout,
+	}
+	for key, dst := range durations {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("parse %s: invalid duration %q", key, v)
+		}
+		*dst = d
+	}
+	return cfg, nil
+}
+
+// Handler runs one job, returning Permanent(err) stops further retries.
+type Handler func(ctx context.Context, job musiclib.Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a job error as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Pool claims jobs from a queue and dispatches them to handlers by kind.
+type Pool struct {
+	cfg      Config
+	queue    musiclib.JobQueue
+	handlers map[string]Handler
+}
+
+func NewPool(cfg Config, queue musiclib.JobQueue) *Pool {
+	return &Pool{
+		cfg:      cfg,
+		queue:    queue,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of a job kind, it must be called before Run.
+func (p *Pool) Handle(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// Run processes jobs until ctx is cancelled and waits for running jobs to finish.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		job, ok, err := p.queue.Claim(ctx, p.cfg.Timeout+p.cfg.Timeout/2)
+		if err != nil && ctx.Err() == nil {
+			log.Error("Could not claim job", "err", err)
+		}
+		if err != nil || !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.cfg.PollInterval):
+			}
+			continue
+		}
+		p.process(ctx, job)
+	}
+}
+
+func (p *Pool) process(ctx context.Context, job musiclib.Job) {
+	err := p.run(ctx, job)
+
+	// The outcome is recorded even when shutting down, so the job is not left leased.
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err == nil {
+		if err := p.queue.Complete(recordCtx, job.Id); err != nil {
+			log.Error("Could not complete job", "job", job.Id, "err", err)
+		}
+		log.Debug("Job done", "job", job.Id, "kind", job.Kind)
+		return
+	}
+
+	var permanent permanentError
+	isPermanent := errors.As(err, &permanent)
+	retryAt := time.Now().Add(p.backoff(job.Attempts))
+	if err := p.queue.Fail(recordCtx, job.Id, err.Error(), retryAt, isPermanent); err != nil {
+		log.Error("Could not record job failure", "job", job.Id, "err", err)
+	}
+	if isPermanent || job.Attempts >= job.MaxAttempts {
+		log.Error("Job is dead", "job", job.Id, "kind", job.Kind, "attempts", job.Attempts, "err", err)
+		return
+	}
+	log.Warn("Job failed, retrying", "job", job.Id, "kind", job.Kind, "attempt", job.Attempts, "retryAt", retryAt, "err", err)
+}
+
+func (p *Pool) run(ctx context.Context, job musiclib.Job) error {
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// backoff returns the delay after the given attempt, doubling from cfg.Backoff.
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.cfg.Backoff
+	for i := 1; i < attempt && delay < p.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.cfg.MaxBackoff)
+}
@@ -0,0 +1,121 @@
+package memstore
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// matchesFilter mirrors the WHERE clause built by the Postgres store.
+func matchesFilter(song musiclib.Song, f musiclib.SongFilter) bool {
+	if f.GroupId != 0 && song.GroupId != f.GroupId {
+		return false
+	}
+	if f.Group != "" && !strings.EqualFold(song.Group, f.Group) {
+		return false
+	}
+	if f.GroupPrefix != "" && !strings.HasPrefix(song.Group, f.GroupPrefix) {
+		return false
+	}
+	if f.GroupContains != "" && !containsFold(song.Group, f.GroupContains) {
+		return false
+	}
+	if f.GroupFuzzy != "" && wordSimilarity(f.GroupFuzzy, song.Group) < fuzzyThreshold {
+		return false
+	}
+	if f.GroupIn != nil && !slices.ContainsFunc(f.GroupIn, func(group string) bool { return strings.EqualFold(group, song.Group) }) {
+		return false
+	}
+	if f.Name != "" && song.Name != f.Name {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(song.Name, f.NamePrefix) {
+		return false
+	}
+	if f.NameContains != "" && !containsFold(song.Name, f.NameContains) {
+		return false
+	}
+	if f.NameFuzzy != "" && wordSimilarity(f.NameFuzzy, song.Name) < fuzzyThreshold {
+		return false
+	}
+	if f.NameIn != nil && !slices.Contains(f.NameIn, song.Name) {
+		return false
+	}
+	if !f.ReleaseDateFrom.IsZero() && (song.ReleaseDate.IsZero() || song.ReleaseDate.Start().Before(f.ReleaseDateFrom.Start())) {
+		return false
+	}
+	if !f.ReleaseDateTo.IsZero() && (song.ReleaseDate.IsZero() || song.ReleaseDate.Start().After(f.ReleaseDateTo.End())) {
+		return false
+	}
+	if f.LinkHost != "" && !matchesHost(song.Link, f.LinkHost) {
+		return false
+	}
+	if f.TextContains != "" && !containsFold(song.Text, f.TextContains) {
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func matchesHost(link, host string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	linkHost := strings.ToLower(u.Hostname())
+	host = strings.ToLower(host)
+	return linkHost == host || strings.HasSuffix(linkHost, "."+host)
+}
+
+// compareSongs orders songs by the sort keys and then by id.
+func compareSongs(a, b musiclib.Song, sorts []musiclib.SongSort) int {
+	for _, sort := range musiclib.NormalizeSort(sorts) {
+		c := compareValues(sort.Field, a.SortValue(sort.Field), b.SortValue(sort.Field))
+		if sort.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// isAfter reports whether song is ordered after the keyset position, an empty position matches every song.
+func isAfter(song musiclib.Song, sorts []musiclib.SongSort, after []string) bool {
+	keys := musiclib.NormalizeSort(sorts)
+	if len(after) == 0 || len(after) != len(keys) {
+		return true
+	}
+	for i, sort := range keys {
+		c := compareValues(sort.Field, song.SortValue(sort.Field), after[i])
+		if sort.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c > 0
+		}
+	}
+	return false
+}
+
+func compareValues(field, a, b string) int {
+	switch field {
+	case musiclib.SortById:
+		idA, _ := strconv.Atoi(a)
+		idB, _ := strconv.Atoi(b)
+		return cmp.Compare(idA, idB)
+	case musiclib.SortByDate:
+		dateA, _ := musiclib.ParseReleaseDate(a)
+		dateB, _ := musiclib.ParseReleaseDate(b)
+		return dateA.Compare(dateB)
+	}
+	return strings.Compare(a, b)
+}
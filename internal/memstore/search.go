@@ -0,0 +1,131 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// Search is a simplified stand-in for Postgres full-text search: every query word has to
+// occur in the name and lyrics, or every word in the group, and the rank is the number
+// of occurrences.
+func (s *SongStore) Search(ctx context.Context, opts musiclib.SongSearchOptions) ([]musiclib.SongSearchResult, error) {
+	terms := searchTerms(opts.Query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	var results []musiclib.SongSearchResult
+	for _, song := range s.songs {
+		result, ok := searchSong(song, terms)
+		if ok {
+			results = append(results, result)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		idA, _ := strconv.Atoi(results[i].Id)
+		idB, _ := strconv.Atoi(results[j].Id)
+		return idA < idB
+	})
+
+	if opts.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(results) {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+func searchTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		word = strings.Trim(word, `"'-`)
+		if word != "" && word != "or" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func searchSong(song musiclib.Song, terms []string) (musiclib.SongSearchResult, bool) {
+	songHaystack := strings.ToLower(song.Name + " " + song.Text)
+	groupHaystack := strings.ToLower(song.Group)
+	if !containsAll(songHaystack, terms) && !containsAll(groupHaystack, terms) {
+		return musiclib.SongSearchResult{}, false
+	}
+	rank := 0
+	for _, term := range terms {
+		rank += strings.Count(songHaystack, term) + strings.Count(groupHaystack, term)
+	}
+
+	result := musiclib.SongSearchResult{
+		Id:          song.Id,
+		Group:       song.Group,
+		Name:        song.Name,
+		ReleaseDate: song.ReleaseDate,
+		Link:        song.Link,
+		Rank:        float64(rank),
+		Verses:      []int{},
+	}
+	var verses []string
+	for _, verse := range strings.Split(song.Text, `\n`) {
+		if verse != "" {
+			verses = append(verses, verse)
+		}
+	}
+	for i, verse := range verses {
+		lower := strings.ToLower(verse)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				if result.Snippet == "" {
+					result.Snippet = highlight(verse, terms)
+				}
+				result.Verses = append(result.Verses, i)
+				break
+			}
+		}
+	}
+	return result, true
+}
+
+func containsAll(haystack string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(haystack, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight wraps case-insensitive occurrences of terms into <mark></mark>.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := 0
+		for _, term := range terms {
+			end := i + len(term)
+			if end <= len(text) && strings.EqualFold(text[i:end], term) && len(term) > matched {
+				matched = len(term)
+			}
+		}
+		if matched == 0 {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString("<mark>" + text[i:i+matched] + "</mark>")
+		i += matched
+	}
+	return b.String()
+}
@@ -0,0 +1,218 @@
+// Package memstore keeps songs in memory, it is meant for tests and local runs without Postgres.
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// SongStore is an in-memory implementation of musiclib.SongStore, safe for concurrent use.
+type SongStore struct {
+	mu          sync.RWMutex
+	songs       map[int]musiclib.Song
+	nextId      int
+	lastVersion int
+}
+
+var _ musiclib.SongStore = (*SongStore)(nil)
+
+// NewSongStore returns a store seeded with the given songs, ids are assigned in order.
+func NewSongStore(seed ...musiclib.Song) *SongStore {
+	s := &SongStore{
+		songs:  make(map[int]musiclib.Song),
+		nextId: 1,
+	}
+	for _, song := range seed {
+		s.insert(song)
+	}
+	return s
+}
+
+func (s *SongStore) insert(song musiclib.Song) musiclib.Song {
+	song.Id = strconv.Itoa(s.nextId)
+	song.Version = s.nextVersion()
+	song.UpdatedAt = time.Now()
+	s.songs[s.nextId] = song
+	s.nextId++
+	return song
+}
+
+// nextVersion returns a version no song of the store has had, like the songVersions
+// sequence of the Postgres store.
+func (s *SongStore) nextVersion() int {
+	s.lastVersion++
+	return s.lastVersion
+}
+
+func (s *SongStore) List(ctx context.Context, opts musiclib.SongListOptions) ([]musiclib.Song, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var songs []musiclib.Song
+	for _, song := range s.songs {
+		if matchesFilter(song, opts.Filter) && isAfter(song, opts.Sort, opts.After) {
+			songs = append(songs, song)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		return compareSongs(songs[i], songs[j], opts.Sort) < 0
+	})
+
+	if opts.Offset >= len(songs) {
+		return nil, nil
+	}
+	songs = songs[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(songs) {
+		songs = songs[:opts.Limit]
+	}
+	return songs, nil
+}
+
+func (s *SongStore) Count(ctx context.Context, filter musiclib.SongFilter) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, song := range s.songs {
+		if matchesFilter(song, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *SongStore) Get(ctx context.Context, id int) (musiclib.Song, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	song, ok := s.songs[id]
+	if !ok {
+		return musiclib.Song{}, musiclib.ErrNotFound
+	}
+	return song, nil
+}
+
+func (s *SongStore) Create(ctx context.Context, song musiclib.Song) (musiclib.Song, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.songs {
+		if sameName(existing, song) {
+			return song, musiclib.ErrConflict
+		}
+	}
+	return s.insert(song), nil
+}
+
+func (s *SongStore) Update(ctx context.Context, song musiclib.Song) error {
+	id, err := strconv.Atoi(song.Id)
+	if err != nil {
+		return musiclib.ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.songs[id]; !ok {
+		return musiclib.ErrNotFound
+	}
+	song.Version = s.nextVersion()
+	song.UpdatedAt = time.Now()
+	s.songs[id] = song
+	return nil
+}
+
+func (s *SongStore) Put(ctx context.Context, song musiclib.Song, version int, create bool) (musiclib.Song, bool, error) {
+	id, err := strconv.Atoi(song.Id)
+	if err != nil {
+		return song, false, musiclib.ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.songs[id]
+	if !exists && !create {
+		return song, false, musiclib.ErrNotFound
+	}
+	if !matchesVersion(current, exists, version) {
+		return song, false, musiclib.ErrVersionMismatch
+	}
+	for existingId, existing := range s.songs {
+		if existingId != id && sameName(existing, song) {
+			return song, false, musiclib.ErrConflict
+		}
+	}
+	song.Version = s.nextVersion()
+	song.UpdatedAt = time.Now()
+	s.songs[id] = song
+	if id >= s.nextId {
+		s.nextId = id + 1
+	}
+	return song, !exists, nil
+}
+
+func (s *SongStore) Patch(ctx context.Context, id int, version int, patch func(musiclib.Song) (musiclib.Song, error)) (musiclib.Song, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.songs[id]
+	if !ok {
+		return musiclib.Song{}, musiclib.ErrNotFound
+	}
+	if !matchesVersion(current, true, version) {
+		return current, musiclib.ErrVersionMismatch
+	}
+	song, err := patch(current)
+	if err != nil {
+		return song, err
+	}
+	song.Id = current.Id
+	for existingId, existing := range s.songs {
+		if existingId != id && sameName(existing, song) {
+			return song, musiclib.ErrConflict
+		}
+	}
+	song.Version = s.nextVersion()
+	song.UpdatedAt = time.Now()
+	s.songs[id] = song
+	return song, nil
+}
+
+func (s *SongStore) Delete(ctx context.Context, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.songs[id]
+	if !ok {
+		return musiclib.ErrNotFound
+	}
+	if !matchesVersion(current, true, version) {
+		return musiclib.ErrVersionMismatch
+	}
+	delete(s.songs, id)
+	return nil
+}
+
+// sameName reports whether two songs have the same name in the same group. Both names
+// compare case-insensitively, like the unique indexes of the Postgres store.
+func sameName(a, b musiclib.Song) bool {
+	return strings.EqualFold(a.Group, b.Group) && strings.EqualFold(a.Name, b.Name)
+}
+
+// matchesVersion reports whether the song, which may not exist, is at the expected version.
+func matchesVersion(song musiclib.Song, exists bool, version int) bool {
+	switch version {
+	case musiclib.AnyVersion:
+		return true
+	case musiclib.NoVersion:
+		return !exists
+	}
+	return exists && song.Version == version
+}
@@ -0,0 +1,121 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// fuzzyThreshold matches the pg_trgm.word_similarity_threshold default.
+const fuzzyThreshold = 0.6
+
+func (s *SongStore) Suggest(ctx context.Context, prefix string, limit int) (musiclib.Suggestions, error) {
+	suggestions := musiclib.Suggestions{
+		Groups: []musiclib.GroupSuggestion{},
+		Songs:  []musiclib.SongSuggestion{},
+	}
+
+	s.mu.RLock()
+	groups := make(map[string]float64)
+	for _, song := range s.songs {
+		if score, ok := suggestScore(prefix, song.Group); ok && score > groups[song.Group] {
+			groups[song.Group] = score
+		}
+		if score, ok := suggestScore(prefix, song.Name); ok {
+			suggestions.Songs = append(suggestions.Songs, musiclib.SongSuggestion{
+				Id:    song.Id,
+				Group: song.Group,
+				Name:  song.Name,
+				Score: score,
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	for group, score := range groups {
+		suggestions.Groups = append(suggestions.Groups, musiclib.GroupSuggestion{Group: group, Score: score})
+	}
+	sort.Slice(suggestions.Groups, func(i, j int) bool {
+		a, b := suggestions.Groups[i], suggestions.Groups[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Group < b.Group
+	})
+	sort.Slice(suggestions.Songs, func(i, j int) bool {
+		a, b := suggestions.Songs[i], suggestions.Songs[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return compareValues(musiclib.SortById, a.Id, b.Id) < 0
+	})
+
+	if limit > 0 && len(suggestions.Groups) > limit {
+		suggestions.Groups = suggestions.Groups[:limit]
+	}
+	if limit > 0 && len(suggestions.Songs) > limit {
+		suggestions.Songs = suggestions.Songs[:limit]
+	}
+	return suggestions, nil
+}
+
+// suggestScore scores a prefix match as 1 and anything else by word similarity.
+func suggestScore(prefix, value string) (float64, bool) {
+	if strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix)) {
+		return 1, true
+	}
+	score := wordSimilarity(prefix, value)
+	return score, score >= fuzzyThreshold
+}
+
+// wordSimilarity approximates pg_trgm word_similarity: the best trigram similarity
+// between query and any run of consecutive words in value.
+func wordSimilarity(query, value string) float64 {
+	queryTrigrams := trigrams(query)
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	best := 0.0
+	for i := range words {
+		for j := i + 1; j <= len(words); j++ {
+			spanTrigrams := trigrams(strings.Join(words[i:j], " "))
+			common := 0
+			for t := range queryTrigrams {
+				if spanTrigrams[t] {
+					common++
+				}
+			}
+			// Trigrams of the span missing from the query do not lower the score.
+			score := float64(common) / float64(len(queryTrigrams))
+			if score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+// trigrams returns the pg_trgm style trigram set: lower-cased words padded with two
+// leading spaces and one trailing space.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,178 @@
+// Package musicinfo is a client for the external music-info API,
+// GET {base}/info?group=...&song=... returning the release date, lyrics and link of a song.
+package musicinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lynxbites/musiclib"
+)
+
+var (
+	// ErrNotFound is returned when the API does not know the song.
+	ErrNotFound = errors.New("song not found in music info API")
+	// ErrRejected is returned for answers another attempt would not change: a 4xx status
+	// other than 404 or a body that is not valid JSON.
+	ErrRejected = errors.New("music info API rejected the request")
+)
+
+type Config struct {
+	// BaseURL is the API root, /info is appended to it.
+	BaseURL string
+	// Timeout limits a single attempt.
+	Timeout time.Duration
+	// Retries is the number of extra attempts after a network error, 429 or 5xx response.
+	Retries int
+	// RetryDelay is the wait before the first retry, it doubles with every further retry.
+	RetryDelay time.Duration
+}
+
+// ConfigFromEnv reads INFOURL, INFOTIMEOUT, INFORETRIES and INFORETRYDELAY. An empty
+// BaseURL means the API is not configured.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		BaseURL:    os.Getenv("INFOURL"),
+		Timeout:    5 * time.Second,
+		Retries:    2,
+		RetryDelay: 200 * time.Millisecond,
+	}
+	if v := os.Getenv("INFOTIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("parse INFOTIMEOUT: invalid duration %q", v)
+		}
+		cfg.Timeout = d
+	}
+	if v := os.Getenv("INFORETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("parse INFORETRIES: invalid value %q", v)
+		}
+		cfg.Retries = n
+	}
+	if v := os.Getenv("INFORETRYDELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("parse INFORETRYDELAY: invalid duration %q", v)
+		}
+		cfg.RetryDelay = d
+	}
+	return cfg, nil
+}
+
+// Client implements musiclib.SongInfoSource.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+var _ musiclib.SongInfoSource = (*Client)(nil)
+
+func New(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("music info base URL must be an absolute http(s) URL, got %q", cfg.BaseURL)
+	}
+	return &Client{
+		cfg:  cfg,
+		http: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// payload is the JSON body of a successful /info response.
+type payload struct {
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// retryableError marks failures worth another attempt.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string { return e.err.Error() }
+func (e retryableError) Unwrap() error { return e.err }
+
+func (c *Client) SongInfo(ctx context.Context, group, name string) (musiclib.SongDetail, error) {
+	query := url.Values{}
+	query.Set("group", group)
+	query.Set("song", name)
+	endpoint := strings.TrimSuffix(c.cfg.BaseURL, "/") + "/info?" + query.Encode()
+
+	delay := c.cfg.RetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		var detail musiclib.SongDetail
+		detail, err = c.fetch(ctx, endpoint)
+		if err == nil {
+			return detail, nil
+		}
+		var retryable retryableError
+		if !errors.As(err, &retryable) || attempt >= c.cfg.Retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return musiclib.SongDetail{}, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return musiclib.SongDetail{}, err
+}
+
+func (c *Client) fetch(ctx context.Context, endpoint string) (musiclib.SongDetail, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return musiclib.SongDetail{}, fmt.Errorf("build info request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return musiclib.SongDetail{}, ctx.Err()
+		}
+		return musiclib.SongDetail{}, retryableError{fmt.Errorf("request info: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return musiclib.SongDetail{}, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		io.Copy(io.Discard, resp.Body)
+		return musiclib.SongDetail{}, retryableError{fmt.Errorf("request info: unexpected status %s", resp.Status)}
+	case resp.StatusCode >= 400:
+		io.Copy(io.Discard, resp.Body)
+		return musiclib.SongDetail{}, fmt.Errorf("request info: %w: unexpected status %s", ErrRejected, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return musiclib.SongDetail{}, fmt.Errorf("request info: unexpected status %s", resp.Status)
+	}
+
+	var body payload
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return musiclib.SongDetail{}, fmt.Errorf("decode info response: %w: %w", ErrRejected, err)
+	}
+	detail := musiclib.SongDetail{
+		Text: body.Text,
+		Link: body.Link,
+	}
+	// A date we cannot read does not spoil the lyrics and the link, Sanitize reports it.
+	detail.ReleaseDate, err = musiclib.ParseReleaseDate(body.ReleaseDate)
+	if err != nil {
+		detail.UnparsedReleaseDate = body.ReleaseDate
+	}
+	return detail, nil
+}
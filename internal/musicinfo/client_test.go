@@ -0,0 +1,135 @@
+package musicinfo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/musicinfo/musicinfotest"
+)
+
+func newClient(t *testing.T, baseURL string) *Client {
+	t.Helper()
+	client, err := New(Config{BaseURL: baseURL, Timeout: time.Second, Retries: 2, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestSongInfo(t *testing.T) {
+	h := musicinfotest.NewHandler()
+	h.Add("Muse", "Supermassive Black Hole", musicinfotest.Song{
+		ReleaseDate: "16.07.2006",
+		Text:        "Ooh baby, don't you know I suffer?",
+		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
+	})
+	server := musicinfotest.NewServer(h)
+	defer server.Close()
+	client := newClient(t, server.URL)
+
+	detail, err := client.SongInfo(context.Background(), "Muse", "Supermassive Black Hole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := musiclib.SongDetail{
+		ReleaseDate: musiclib.ReleaseDate{Year: 2006, Month: 7, Day: 16, Precision: musiclib.PrecisionDay},
+		Text:        "Ooh baby, don't you know I suffer?",
+		Link:        "https://www.youtube.com/watch?v=Xsp3_a-PMTw",
+	}
+	if detail != want {
+		t.Errorf("detail = %+v, want %+v", detail, want)
+	}
+
+	_, err = client.SongInfo(context.Background(), "Muse", "Uprising")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown song: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSongInfoRetries(t *testing.T) {
+	h := musicinfotest.NewHandler()
+	h.Add("Muse", "Uprising", musicinfotest.Song{ReleaseDate: "2009"})
+	server := musicinfotest.NewServer(h)
+	defer server.Close()
+	client := newClient(t, server.URL)
+
+	h.FailNext(2)
+	if _, err := client.SongInfo(context.Background(), "Muse", "Uprising"); err != nil {
+		t.Fatalf("2 failures with 2 retries: %v", err)
+	}
+
+	h.FailNext(3)
+	_, err := client.SongInfo(context.Background(), "Muse", "Uprising")
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("3 failures with 2 retries: err = %v, want a retryable error", err)
+	}
+}
+
+func TestSongInfoInvalidReleaseDate(t *testing.T) {
+	h := musicinfotest.NewHandler()
+	h.Add("Muse", "Uprising", musicinfotest.Song{ReleaseDate: "autumn 2009", Text: "Paranoia is in bloom", Link: "https://example.com/uprising"})
+	server := musicinfotest.NewServer(h)
+	defer server.Close()
+	client := newClient(t, server.URL)
+
+	detail, err := client.SongInfo(context.Background(), "Muse", "Uprising")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !detail.ReleaseDate.IsZero() || detail.UnparsedReleaseDate != "autumn 2009" {
+		t.Errorf("detail = %+v, want the date left unparsed", detail)
+	}
+	if detail.Text != "Paranoia is in bloom" || detail.Link != "https://example.com/uprising" {
+		t.Errorf("detail = %+v, want the text and link kept", detail)
+	}
+
+	invalid := detail.Sanitize()
+	if len(invalid) != 1 || invalid[0].Field != "releaseDate" {
+		t.Errorf("Sanitize() = %+v, want the release date reported", invalid)
+	}
+	if detail.UnparsedReleaseDate != "" || detail.Text == "" {
+		t.Errorf("sanitized detail = %+v, want only the date dropped", detail)
+	}
+}
+
+func TestSongInfoRejected(t *testing.T) {
+	var requests atomic.Int32
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"bad request", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+		}},
+		{"forbidden", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}},
+		{"invalid JSON", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"releaseDate":`))
+		}},
+	}
+	for _, tt := range tests {
+		requests.Store(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			tt.handler(w, r)
+		}))
+		client := newClient(t, server.URL)
+
+		_, err := client.SongInfo(context.Background(), "Muse", "Uprising")
+		if !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: err = %v, want ErrRejected", tt.name, err)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("%s: %d requests, want no retries", tt.name, n)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,76 @@
+// Package musicinfotest provides a fake music-info API for tests and local runs.
+package musicinfotest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Song is a fake /info answer.
+type Song struct {
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// Handler answers GET /info?group=...&song=... from an in-memory catalogue.
+type Handler struct {
+	mu    sync.Mutex
+	songs map[[2]string]Song
+	// failures makes the next n requests answer 503, to exercise retries.
+	failures int
+}
+
+func NewHandler() *Handler {
+	return &Handler{songs: make(map[[2]string]Song)}
+}
+
+// Add registers the answer for a group and song name.
+func (h *Handler) Add(group, song string, info Song) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.songs[[2]string{group, song}] = info
+}
+
+// FailNext makes the next n requests fail with 503 Service Unavailable.
+func (h *Handler) FailNext(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = n
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Path != "/info" {
+		http.NotFound(w, r)
+		return
+	}
+	group, song := r.URL.Query().Get("group"), r.URL.Query().Get("song")
+	if group == "" || song == "" {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	if h.failures > 0 {
+		h.failures--
+		h.mu.Unlock()
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	info, ok := h.songs[[2]string{group, song}]
+	h.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// NewServer starts a fake API on a local port, its URL is the client base URL.
+func NewServer(h *Handler) *httptest.Server {
+	return httptest.NewServer(h)
+}
@@ -0,0 +1,83 @@
+// Package problem writes RFC 7807 application/problem+json error responses.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lynxbites/musiclib"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// Problem types beyond about:blank, which means the status code says it all.
+const (
+	TypeValidation = "/problems/validation"
+	TypeInternal   = "/problems/internal"
+)
+
+// Problem is the body of every error response.
+type Problem struct {
+	// Type is a URI reference identifying the kind of problem.
+	Type string `json:"type" example:"about:blank"`
+	// Title is the status text of Status.
+	Title  string `json:"title" example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty" example:"Song not found"`
+	// Instance is the path of the request.
+	Instance string `json:"instance,omitempty" example:"/api/v1/songs/7"`
+	// RequestId is also sent in the X-Request-Id header and logged with the request.
+	RequestId string `json:"requestId,omitempty" example:"host/abcdef-000042"`
+	// Errors lists the invalid fields of a validation problem.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a single invalid field of a request body, path or query.
+type FieldError = musiclib.FieldError
+
+// New returns a problem of type about:blank for the request.
+func New(r *http.Request, status int, detail string) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+		p.RequestId = middleware.GetReqID(r.Context())
+	}
+	return p
+}
+
+// Write writes p as the response.
+func (p Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a problem with the status and detail, detail may be empty.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	New(r, status, detail).Write(w)
+}
+
+// Validation writes a 400 problem listing the invalid fields.
+func Validation(w http.ResponseWriter, r *http.Request, errs ...FieldError) {
+	p := New(r, http.StatusBadRequest, "The request has invalid fields")
+	p.Type = TypeValidation
+	p.Errors = errs
+	p.Write(w)
+}
+
+// Internal writes a 500 problem without details, errors may carry database internals.
+// The caller logs the error, the request id in the response ties the two together.
+func Internal(w http.ResponseWriter, r *http.Request) {
+	p := New(r, http.StatusInternalServerError, "The server could not complete the request, report the request id if the problem persists")
+	p.Type = TypeInternal
+	p.Write(w)
+}
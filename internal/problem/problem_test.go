@@ -0,0 +1,57 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidation(t *testing.T) {
+	w := httptest.NewRecorder()
+	Validation(w, httptest.NewRequest("GET", "/api/v1/songs/?page=x", nil), FieldError{Field: "page", Message: "must be a positive integer"})
+
+	if w.Code != 400 || w.Header().Get("Content-Type") != ContentType || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("status = %d, headers = %v, want a 400 problem", w.Code, w.Header())
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != TypeValidation || p.Title != "Bad Request" || p.Instance != "/api/v1/songs/" {
+		t.Errorf("problem = %+v, want a validation problem of the path", p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0] != (FieldError{Field: "page", Message: "must be a positive integer"}) {
+		t.Errorf("errors = %+v, want the page error", p.Errors)
+	}
+}
+
+func TestInternal(t *testing.T) {
+	w := httptest.NewRecorder()
+	Internal(w, httptest.NewRequest("GET", "/api/v1/songs/1", nil))
+
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 500 || p.Type != TypeInternal || p.Status != 500 || p.Errors != nil {
+		t.Errorf("status = %d, problem = %+v, want an internal problem", w.Code, p)
+	}
+}
+
+func TestErrorWithoutDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	Error(w, nil, 409, "")
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"detail", "instance", "requestId", "errors"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("body has an empty %s: %v", key, body)
+		}
+	}
+	if body["title"] != "Conflict" || body["type"] != "about:blank" {
+		t.Errorf("body = %v, want a plain conflict", body)
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// window is the time an empty bucket takes to fill up.
+func (l Limit) window() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// fill adds the tokens earned since the last call.
+func (b *bucket) fill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// result is the outcome of taking a token from a bucket.
+type result struct {
+	allowed   bool
+	limit     int
+	remaining int
+	// reset is the time until the bucket is full again.
+	reset time.Duration
+	// retryAfter is the time until the next token, zero when allowed.
+	retryAfter time.Duration
+}
+
+// buckets holds a bucket per key, idle buckets are dropped once they are full again.
+type buckets struct {
+	mu        sync.Mutex
+	m         map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets() *buckets {
+	return &buckets{m: make(map[string]*bucket)}
+}
+
+// take removes a token from the bucket of key, creating a full one if there is none.
+func (bs *buckets) take(key string, limit Limit, now time.Time) result {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if now.Sub(bs.lastSweep) > time.Minute {
+		bs.sweep(now)
+	}
+
+	b, ok := bs.m[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		bs.m[key] = b
+	}
+	b.fill(now)
+
+	res := result{limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.allowed = true
+	} else {
+		res.retryAfter = time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	res.remaining = int(b.tokens)
+	res.reset = time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second))
+	return res
+}
+
+func (bs *buckets) sweep(now time.Time) {
+	for key, b := range bs.m {
+		if now.Sub(b.last) >= b.limit.window() {
+			delete(bs.m, key)
+		}
+	}
+	bs.lastSweep = now
+}
@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsTake(t *testing.T) {
+	bs := newBuckets()
+	limit := Limit{Rate: 2, Burst: 2}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{500 * time.Millisecond, true, 0, 0},
+		// Two seconds idle refill the bucket, but not beyond the burst.
+		{2500 * time.Millisecond, true, 1, 0},
+	}
+	for i, tt := range tests {
+		res := bs.take("ip:192.0.2.1", limit, start.Add(tt.after))
+		if res.allowed != tt.allowed || res.remaining != tt.remaining || res.retryAfter != tt.retryAfter {
+			t.Errorf("take %d = %+v, want allowed %v, remaining %d, retry after %s", i+1, res, tt.allowed, tt.remaining, tt.retryAfter)
+		}
+		if res.limit != 2 {
+			t.Errorf("take %d limit = %d, want the burst", i+1, res.limit)
+		}
+	}
+}
+
+func TestBucketsReset(t *testing.T) {
+	bs := newBuckets()
+	limit := Limit{Rate: 1, Burst: 3}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	res := bs.take("ip:192.0.2.1", limit, now)
+	if res.reset != time.Second {
+		t.Errorf("reset after one token = %s, want 1s", res.reset)
+	}
+	res = bs.take("ip:192.0.2.1", limit, now)
+	if res.reset != 2*time.Second {
+		t.Errorf("reset after two tokens = %s, want 2s", res.reset)
+	}
+}
+
+func TestBucketsKeys(t *testing.T) {
+	bs := newBuckets()
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	bs.take("ip:192.0.2.1", limit, now)
+	if res := bs.take("ip:192.0.2.2", limit, now); !res.allowed {
+		t.Error("another client shares the bucket")
+	}
+	if res := bs.take("ip:192.0.2.1", limit, now); res.allowed {
+		t.Error("an empty bucket allowed a request")
+	}
+	// A changed limit starts over with a full bucket.
+	if res := bs.take("ip:192.0.2.1", Limit{Rate: 1, Burst: 5}, now); !res.allowed || res.remaining != 4 {
+		t.Errorf("take with a new limit = %+v, want a full bucket", res)
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	bs := newBuckets()
+	limit := Limit{Rate: 1, Burst: 10}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	bs.take("ip:192.0.2.1", limit, now)
+	bs.take("ip:192.0.2.2", limit, now.Add(60*time.Second))
+	// Buckets are swept once a minute, the first one is full again after its 10s window.
+	bs.take("ip:192.0.2.3", limit, now.Add(61*time.Second))
+	if _, ok := bs.m["ip:192.0.2.1"]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := bs.m["ip:192.0.2.2"]; !ok {
+		t.Error("bucket in use was dropped")
+	}
+}
@@ -0,0 +1,238 @@
+// Package ratelimit throttles API clients with token buckets kept in memory and
+// enforces the daily request quotas of API keys kept in Postgres.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+type Config struct {
+	// Default is the limit of routes without an entry in Routes, a zero Rate disables it.
+	Default Limit
+	// Routes maps "METHOD /pattern" to the limit of that route, patterns are the
+	// chi route patterns without a trailing slash, e.g. "GET /api/v1/songs/{songId}".
+	Routes map[string]Limit
+}
+
+// ConfigFromEnv reads RATELIMIT, RATEBURST and RATELIMITROUTES. RATELIMIT is a rate
+// like 10/s, 300/m or 5000/h, RATEBURST defaults to one second worth of it. RATELIMITROUTES
+// is a semicolon separated list of "METHOD /pattern=rate" entries with an optional ":burst"
+// suffix, e.g. "GET /api/v1/songs=2/s:10;GET /api/v1/songs/search=1/s", like CACHECONTROLROUTES. Without RATELIMIT and RATELIMITROUTES
+// nothing is limited.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Routes: make(map[string]Limit)}
+
+	if v := os.Getenv("RATELIMIT"); v != "" {
+		rate, err := parseRate(v)
+		if err != nil {
+			return cfg, fmt.Errorf("parse RATELIMIT: %w", err)
+		}
+		cfg.Default = Limit{Rate: rate, Burst: int(math.Max(1, math.Ceil(rate)))}
+	}
+	if v := os.Getenv("RATEBURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("parse RATEBURST: invalid value %q", v)
+		}
+		cfg.Default.Burst = n
+	}
+
+	if v := os.Getenv("RATELIMITROUTES"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			route, spec, found := strings.Cut(strings.TrimSpace(entry), "=")
+			method, pattern, ok := strings.Cut(strings.TrimSpace(route), " ")
+			if !found || !ok {
+				return cfg, fmt.Errorf("parse RATELIMITROUTES: invalid entry %q", entry)
+			}
+			limit, err := parseLimit(spec)
+			if err != nil {
+				return cfg, fmt.Errorf("parse RATELIMITROUTES: %w", err)
+			}
+			cfg.Routes[routeKey(method, pattern)] = limit
+		}
+	}
+	return cfg, nil
+}
+
+// parseLimit parses "rate" or "rate:burst".
+func parseLimit(spec string) (Limit, error) {
+	rateSpec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+	rate, err := parseRate(rateSpec)
+	if err != nil {
+		return Limit{}, err
+	}
+	limit := Limit{Rate: rate, Burst: int(math.Max(1, math.Ceil(rate)))}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstSpec)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid burst %q", burstSpec)
+		}
+	}
+	return limit, nil
+}
+
+// parseRate parses "N/s", "N/m" or "N/h" into tokens per second.
+func parseRate(spec string) (float64, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(spec), "/")
+	n, err := strconv.ParseFloat(count, 64)
+	if !found || err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q", spec)
+	}
+	switch unit {
+	case "s":
+		return n, nil
+	case "m":
+		return n / 60, nil
+	case "h":
+		return n / 3600, nil
+	}
+	return 0, fmt.Errorf("invalid rate %q, use /s, /m or /h", spec)
+}
+
+func routeKey(method, pattern string) string {
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return strings.ToUpper(method) + " " + pattern
+}
+
+// Limiter is the middleware enforcing the configured limits and API key quotas.
+type Limiter struct {
+	cfg     Config
+	buckets *buckets
+	quotas  musiclib.APIKeyStore
+	now     func() time.Time
+}
+
+// New returns a limiter, quotas may be nil when API keys are not enabled.
+func New(cfg Config, quotas musiclib.APIKeyStore) *Limiter {
+	return &Limiter{cfg: cfg, buckets: newBuckets(), quotas: quotas, now: time.Now}
+}
+
+// Enabled reports whether there is any limit to enforce besides quotas.
+func (l *Limiter) Enabled() bool {
+	return l.cfg.Default.Rate > 0 || len(l.cfg.Routes) > 0
+}
+
+// Middleware limits requests per client and route. Clients are told apart by API key,
+// then by user and then by remote address, so it has to run after authentication.
+// Routes with their own limit get their own bucket, all others share the default one.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.FromContext(r.Context())
+
+		route := l.route(r)
+		limit, ok := l.cfg.Routes[route]
+		if !ok {
+			limit, route = l.cfg.Default, ""
+		}
+		if limit.Rate > 0 {
+			res := l.buckets.take(clientKey(r, principal)+" "+route, limit, l.now())
+			setHeaders(w, res.limit, res.remaining, res.reset, limit.window())
+			if !res.allowed {
+				tooManyRequests(w, r, res.retryAfter, "Rate limit exceeded")
+				return
+			}
+		}
+
+		// The quota is charged last, requests turned away by the bucket do not use it up.
+		if principal != nil && principal.APIKeyId > 0 && principal.DailyQuota > 0 && l.quotas != nil {
+			if !l.useQuota(w, r, principal) {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// useQuota counts the request against the daily quota of the API key and writes
+// a 429 response once it is used up.
+func (l *Limiter) useQuota(w http.ResponseWriter, r *http.Request, principal *auth.Principal) bool {
+	now := l.now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+
+	used, err := l.quotas.UseQuota(r.Context(), principal.APIKeyId, principal.DailyQuota)
+	if errors.Is(err, musiclib.ErrQuotaExceeded) {
+		setHeaders(w, principal.DailyQuota, 0, midnight.Sub(now), 24*time.Hour)
+		log.Debug("Daily quota used up", "apiKeyId", principal.APIKeyId)
+		tooManyRequests(w, r, midnight.Sub(now), "The daily quota of the API key is used up")
+		return false
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to count API key quota: %v", err)
+		problem.Internal(w, r)
+		return false
+	}
+	w.Header().Set("X-Quota-Limit", strconv.Itoa(principal.DailyQuota))
+	w.Header().Set("X-Quota-Remaining", strconv.Itoa(max(principal.DailyQuota-used, 0)))
+	return true
+}
+
+// route returns the key of the route the request is going to, matched against the
+// whole router since sub-routers have not run yet.
+func (l *Limiter) route(r *http.Request) string {
+	if len(l.cfg.Routes) == 0 {
+		return ""
+	}
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, r.URL.Path) {
+		return ""
+	}
+	return routeKey(r.Method, match.RoutePattern())
+}
+
+// clientKey identifies the client of a request. Remote addresses are taken as they
+// are, put a proxy in front that rewrites them if the API is not reached directly.
+func clientKey(r *http.Request, principal *auth.Principal) string {
+	switch {
+	case principal != nil && principal.APIKeyId > 0:
+		return "key:" + strconv.Itoa(principal.APIKeyId)
+	case principal != nil && principal.UserId > 0:
+		return "user:" + strconv.Itoa(principal.UserId)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// setHeaders writes the RateLimit headers of the IETF ratelimit headers draft.
+func setHeaders(w http.ResponseWriter, limit, remaining int, reset, window time.Duration) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit, max(seconds(window), 1)))
+}
+
+func tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, detail string) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds(retryAfter), 1)))
+	log.Debug("429 Too Many Requests")
+	problem.Error(w, r, 429, detail)
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
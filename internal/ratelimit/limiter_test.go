@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
+)
+
+// fakeQuotas implements the quota part of musiclib.APIKeyStore.
+type fakeQuotas struct {
+	musiclib.APIKeyStore
+	used map[int]int
+}
+
+func (q *fakeQuotas) UseQuota(ctx context.Context, id, quota int) (int, error) {
+	if q.used[id] >= quota {
+		return q.used[id], musiclib.ErrQuotaExceeded
+	}
+	q.used[id]++
+	return q.used[id], nil
+}
+
+// newTestLimiter returns a limiter whose clock stands at now.
+func newTestLimiter(cfg Config, quotas musiclib.APIKeyStore, now time.Time) *Limiter {
+	l := New(cfg, quotas)
+	l.now = func() time.Time { return now }
+	return l
+}
+
+// serveLimited runs a GET as principal, nil means anonymous, through the limiter.
+func serveLimited(l *Limiter, target, remoteAddr string, principal *auth.Principal) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Use(l.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	r.Get("/api/v1/songs", ok)
+	r.Get("/api/v1/songs/{songId}", ok)
+
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remoteAddr
+	if principal != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLimiterDefault(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(Config{Default: Limit{Rate: 1, Burst: 1}}, nil, now)
+
+	w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", nil)
+	if w.Code != 204 || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Policy") != "1;w=1" {
+		t.Fatalf("first request: status = %d, headers = %v", w.Code, w.Header())
+	}
+	w = serveLimited(l, "/api/v1/songs/1", "192.0.2.1:4321", nil)
+	if w.Code != 429 || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second request: status = %d, Retry-After = %q, want 429 after 1s", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Users are told apart from their address, even behind the same one.
+	user := &auth.Principal{UserId: 7, Role: musiclib.RoleViewer}
+	if w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", user); w.Code != 204 {
+		t.Errorf("user: status = %d, want 204", w.Code)
+	}
+	if w := serveLimited(l, "/api/v1/songs", "192.0.2.2:1234", nil); w.Code != 204 {
+		t.Errorf("other address: status = %d, want 204", w.Code)
+	}
+}
+
+func TestLimiterRoutes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Default: Limit{Rate: 10, Burst: 10},
+		Routes:  map[string]Limit{"GET /api/v1/songs/{songId}": {Rate: 1, Burst: 1}},
+	}
+	l := newTestLimiter(cfg, nil, now)
+
+	if w := serveLimited(l, "/api/v1/songs/1", "192.0.2.1:1234", nil); w.Code != 204 {
+		t.Fatalf("status = %d, want 204", w.Code)
+	}
+	// The route limit covers every song id.
+	if w := serveLimited(l, "/api/v1/songs/2", "192.0.2.1:1234", nil); w.Code != 429 {
+		t.Errorf("status = %d, want 429 from the route limit", w.Code)
+	}
+	w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", nil)
+	if w.Code != 204 || w.Header().Get("RateLimit-Limit") != "10" {
+		t.Errorf("other route: status = %d, RateLimit-Limit = %q, want the default limit", w.Code, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestLimiterQuota(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	quotas := &fakeQuotas{used: map[int]int{}}
+	l := newTestLimiter(Config{}, quotas, now)
+	key := &auth.Principal{APIKeyId: 3, Scopes: []musiclib.Scope{musiclib.ScopeReadSongs}, DailyQuota: 2}
+
+	for want := 1; want >= 0; want-- {
+		w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", key)
+		if w.Code != 204 || w.Header().Get("X-Quota-Limit") != "2" {
+			t.Fatalf("status = %d, X-Quota-Limit = %q, want 204 within the quota", w.Code, w.Header().Get("X-Quota-Limit"))
+		}
+		if got := w.Header().Get("X-Quota-Remaining"); got != strconv.Itoa(want) {
+			t.Errorf("X-Quota-Remaining = %q, want %d", got, want)
+		}
+	}
+
+	// The quota resets at midnight UTC, an hour away.
+	w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", key)
+	if w.Code != 429 || w.Header().Get("Retry-After") != "3600" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("status = %d, headers = %v, want 429 until midnight", w.Code, w.Header())
+	}
+
+	// Keys without a quota and users are never counted.
+	serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", &auth.Principal{APIKeyId: 4, Scopes: key.Scopes})
+	serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", &auth.Principal{UserId: 1, Role: musiclib.RoleAdmin})
+	if len(quotas.used) != 1 {
+		t.Errorf("quotas used = %v, want only key 3", quotas.used)
+	}
+}
+
+func TestLimiterQuotaAfterBucket(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	quotas := &fakeQuotas{used: map[int]int{}}
+	l := newTestLimiter(Config{Default: Limit{Rate: 1, Burst: 1}}, quotas, now)
+	key := &auth.Principal{APIKeyId: 3, Scopes: []musiclib.Scope{musiclib.ScopeReadSongs}, DailyQuota: 100}
+
+	serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", key)
+	if w := serveLimited(l, "/api/v1/songs", "192.0.2.1:1234", key); w.Code != 429 {
+		t.Fatalf("status = %d, want 429 from the bucket", w.Code)
+	}
+	if quotas.used[3] != 1 {
+		t.Errorf("quota used = %d, want only the request that passed the bucket", quotas.used[3])
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("RATELIMIT", "300/m")
+	t.Setenv("RATEBURST", "")
+	t.Setenv("RATELIMITROUTES", "GET /api/v1/songs/=2/s:10; ;get /api/v1/songs/search=1/h")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Default != (Limit{Rate: 5, Burst: 5}) {
+		t.Errorf("Default = %+v, want 5/s with a burst of 5", cfg.Default)
+	}
+	want := map[string]Limit{
+		"GET /api/v1/songs":        {Rate: 2, Burst: 10},
+		"GET /api/v1/songs/search": {Rate: 1.0 / 3600, Burst: 1},
+	}
+	if len(cfg.Routes) != len(want) {
+		t.Fatalf("Routes = %+v, want %+v", cfg.Routes, want)
+	}
+	for route, limit := range want {
+		if cfg.Routes[route] != limit {
+			t.Errorf("Routes[%q] = %+v, want %+v", route, cfg.Routes[route], limit)
+		}
+	}
+
+	for _, routes := range []string{"GET /api/v1/songs", "/api/v1/songs=1/s", "GET /api/v1/songs=1/d", "GET /api/v1/songs=1/s:0"} {
+		t.Setenv("RATELIMITROUTES", routes)
+		if _, err := ConfigFromEnv(); err == nil {
+			t.Errorf("RATELIMITROUTES=%q was accepted", routes)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetAlbumList godoc
+// @Summary      Get albums
+// @Description  Gets list of albums without tracks, ordered by group, release date and title.
+// @Tags         Albums
+// @Param   groupId      query     int     false 	"Only albums of this group."
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.Album "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of albums"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/albums [get]
+func (h *handler) getAlbumList(w http.ResponseWriter, r *http.Request) {
+	paramGroupId := r.URL.Query().Get("groupId")
+
+	var err error
+	opts := musiclib.AlbumListOptions{}
+
+	if paramGroupId != "" {
+		opts.GroupId, err = parseGroupId(paramGroupId)
+		if err != nil {
+			log.Debug("400 Bad Request")
+			problem.Validation(w, r, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+			return
+		}
+	}
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+	opts.Limit = items
+	opts.Offset = (page - 1) * items
+
+	albums, err := h.albums.List(r.Context(), opts)
+	if err != nil {
+		log.Error("Encountered error when trying to get album list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if albums == nil {
+		albums = []musiclib.Album{}
+	}
+
+	total, err := h.albums.Count(r.Context(), opts.GroupId)
+	if err != nil {
+		log.Error("Encountered error when trying to count albums: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(albums)
+	log.Debug("200 OK")
+}
+
+// GetAlbum godoc
+// @Summary      Get album
+// @Description  Get album by id with its tracks expanded, ordered by disc and track number.
+// @Tags         Albums
+// @Produce      json
+// @Param   	 albumId      path     int     true  "Id of an album."
+// @Success      200  {object}  musiclib.Album
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/albums/{albumId} [get]
+func (h *handler) getAlbum(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAlbumId(chi.URLParam(r, "albumId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "albumId", Message: "must be a positive integer"})
+		return
+	}
+	h.writeAlbum(w, r, id)
+}
+
+// AddAlbum godoc
+// @Summary      Post album
+// @Description  Create an album. Title and group are required, the group is matched by name regardless of case and created when missing. Titles are unique within a group.
+// @Tags         Albums
+// @Accept       json
+// @Param 		 json body string true "Album JSON Object" SchemaExample({"title":"Album title", "group":"Group name", "releaseDate":"2006-07", "coverLink":"https://example.com/cover.jpg"})
+// @Produce      json
+// @Success      201  {object}  musiclib.Album
+// @Header       201  {string}  Location  "URL of the album"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/albums [post]
+func (h *handler) addAlbum(w http.ResponseWriter, r *http.Request) {
+	var albumPost musiclib.AlbumPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&albumPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if albumPost.Title == nil || strings.TrimSpace(*albumPost.Title) == "" || albumPost.Group == nil || strings.TrimSpace(*albumPost.Group) == "" {
+		log.Debug("400 Bad Request: Invalid JSON")
+		var errs []problem.FieldError
+		if albumPost.Title == nil || strings.TrimSpace(*albumPost.Title) == "" {
+			errs = append(errs, problem.FieldError{Field: "title", Message: "is required"})
+		}
+		if albumPost.Group == nil || strings.TrimSpace(*albumPost.Group) == "" {
+			errs = append(errs, problem.FieldError{Field: "group", Message: "is required"})
+		}
+		problem.Validation(w, r, errs...)
+		return
+	}
+
+	album := musiclib.Album{
+		Title: strings.TrimSpace(*albumPost.Title),
+		Group: strings.TrimSpace(*albumPost.Group),
+	}
+	if albumPost.ReleaseDate != nil {
+		album.ReleaseDate = *albumPost.ReleaseDate
+	}
+	if albumPost.CoverLink != nil {
+		album.CoverLink = *albumPost.CoverLink
+	}
+
+	album, err = h.albums.Create(r.Context(), album)
+	if errors.Is(err, musiclib.ErrAlbumConflict) {
+		log.Debug("409 Conflict: Album already exists")
+		problem.Error(w, r, 409, "Album already exists")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to insert album: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/albums/%d", album.Id))
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(album)
+	log.Debug("201 Created")
+}
+
+// DeleteAlbum godoc
+// @Summary      Delete album
+// @Description  Delete an album and its track list, the songs are kept.
+// @Tags         Albums
+// @Param   	 albumId      path     int     true  "Id of an album to delete"
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/albums/{albumId} [delete]
+func (h *handler) deleteAlbum(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAlbumId(chi.URLParam(r, "albumId"))
+	if err != nil {
+		log.Debug("400 Bad request")
+		problem.Validation(w, r, problem.FieldError{Field: "albumId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.albums.Delete(r.Context(), id)
+	if err != nil && !errors.Is(err, musiclib.ErrAlbumNotFound) {
+		log.Error("Encountered error when trying to delete album: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// AddAlbumTrack godoc
+// @Summary      Add track
+// @Description  Put a song on the album. Disc defaults to 1, without number the song is appended to the disc, otherwise the tracks from that number on move down by one.
+// @Tags         Albums
+// @Accept       json
+// @Param 		 json body string true "Track JSON Object" SchemaExample({"songId":1, "disc":1, "number":2})
+// @Param   	 albumId      path     int     true  "Id of an album."
+// @Produce      json
+// @Success      200  {object}  musiclib.Album
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Album or song not found"
+// @Failure      409  {object}  problem.Problem  "Song is already on the album"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/albums/{albumId}/tracks [post]
+func (h *handler) addAlbumTrack(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAlbumId(chi.URLParam(r, "albumId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "albumId", Message: "must be a positive integer"})
+		return
+	}
+
+	var track musiclib.TrackPosition
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&track)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if !isTrackValid(track) {
+		log.Debug("400 Bad Request: Invalid track")
+		problem.Validation(w, r, trackErrors("", track)...)
+		return
+	}
+
+	err = h.albums.AddTrack(r.Context(), id, track)
+	if !h.checkTrackError(w, r, err) {
+		return
+	}
+	h.writeAlbum(w, r, id)
+}
+
+// SetAlbumTracks godoc
+// @Summary      Set tracks
+// @Description  Replace the track list of the album, use it to reorder tracks. Songs are numbered per disc in the given order, number fields are ignored.
+// @Tags         Albums
+// @Accept       json
+// @Param 		 json body string true "Array of tracks" SchemaExample([{"songId":3}, {"songId":1}, {"songId":2, "disc":2}])
+// @Param   	 albumId      path     int     true  "Id of an album."
+// @Produce      json
+// @Success      200  {object}  musiclib.Album
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Album or song not found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/albums/{albumId}/tracks [put]
+func (h *handler) setAlbumTracks(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAlbumId(chi.URLParam(r, "albumId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "albumId", Message: "must be a positive integer"})
+		return
+	}
+
+	var tracks []musiclib.TrackPosition
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&tracks)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	seen := make(map[int]bool)
+	for i, track := range tracks {
+		if !isTrackValid(track) || seen[track.SongId] {
+			log.Debug("400 Bad Request: Invalid or repeated track")
+			field := fmt.Sprintf("[%d].", i)
+			errs := trackErrors(field, track)
+			if seen[track.SongId] {
+				errs = append(errs, problem.FieldError{Field: field + "songId", Message: "must appear once"})
+			}
+			problem.Validation(w, r, errs...)
+			return
+		}
+		seen[track.SongId] = true
+	}
+
+	err = h.albums.SetTracks(r.Context(), id, tracks)
+	if !h.checkTrackError(w, r, err) {
+		return
+	}
+	h.writeAlbum(w, r, id)
+}
+
+// RemoveAlbumTrack godoc
+// @Summary      Remove track
+// @Description  Take a song off the album, the following tracks of its disc move up by one.
+// @Tags         Albums
+// @Param   	 albumId      path     int     true  "Id of an album."
+// @Param   	 songId      path     int     true  "Id of a song on the album."
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Album not found or song not on the album"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/albums/{albumId}/tracks/{songId} [delete]
+func (h *handler) removeAlbumTrack(w http.ResponseWriter, r *http.Request) {
+	albumId, err := parseAlbumId(chi.URLParam(r, "albumId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "albumId", Message: "must be a positive integer"})
+		return
+	}
+	songId, err := parseSongId(chi.URLParam(r, "songId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.albums.RemoveTrack(r.Context(), albumId, songId)
+	if !h.checkTrackError(w, r, err) {
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// writeAlbum writes the album with its tracks.
+func (h *handler) writeAlbum(w http.ResponseWriter, r *http.Request, id int) {
+	album, err := h.albums.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrAlbumNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Album not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get album: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(album)
+	log.Debug("200 OK")
+}
+
+// checkTrackError writes the response for a failed track list change and reports
+// whether the change succeeded.
+func (h *handler) checkTrackError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, musiclib.ErrAlbumNotFound):
+		log.Debug("404 Not Found: Album does not exist")
+		problem.Error(w, r, 404, "Album not found")
+	case errors.Is(err, musiclib.ErrNotFound):
+		log.Debug("404 Not Found: Song does not exist")
+		problem.Error(w, r, 404, "Song not found")
+	case errors.Is(err, musiclib.ErrTrackNotFound):
+		log.Debug("404 Not Found: Song is not on the album")
+		problem.Error(w, r, 404, "Song is not on the album")
+	case errors.Is(err, musiclib.ErrTrackConflict):
+		log.Debug("409 Conflict: Song is already on the album")
+		problem.Error(w, r, 409, "Song is already on the album")
+	default:
+		log.Error("Encountered error when trying to change tracks: %v", err)
+		problem.Internal(w, r)
+	}
+	return false
+}
+
+func isTrackValid(track musiclib.TrackPosition) bool {
+	return track.SongId > 0 && track.Disc >= 0 && track.Number >= 0
+}
+
+// trackErrors lists the invalid fields of track, prefix locates it in the request body.
+func trackErrors(prefix string, track musiclib.TrackPosition) []problem.FieldError {
+	var errs []problem.FieldError
+	if track.SongId <= 0 {
+		errs = append(errs, problem.FieldError{Field: prefix + "songId", Message: "must be a positive integer"})
+	}
+	if track.Disc < 0 {
+		errs = append(errs, problem.FieldError{Field: prefix + "disc", Message: "must not be negative"})
+	}
+	if track.Number < 0 {
+		errs = append(errs, problem.FieldError{Field: prefix + "number", Message: "must not be negative"})
+	}
+	return errs
+}
+
+// parseAlbumId parses an albumId path parameter, only positive integers are valid.
+func parseAlbumId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("album id must be positive")
+	}
+	return id, nil
+}
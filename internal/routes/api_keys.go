@@ -0,0 +1,347 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetAPIKeyList godoc
+// @Summary      Get API keys
+// @Description  Gets list of API keys ordered by id, with pagination. Admins only, the keys themselves are never shown again after minting.
+// @Tags         API keys
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.APIKey "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of API keys"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys [get]
+func (h *handler) getAPIKeyList(w http.ResponseWriter, r *http.Request) {
+
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	keys, err := h.apiKeys.List(r.Context(), items, (page-1)*items)
+	if err != nil {
+		log.Error("Encountered error when trying to get API key list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if keys == nil {
+		keys = []musiclib.APIKey{}
+	}
+
+	total, err := h.apiKeys.Count(r.Context())
+	if err != nil {
+		log.Error("Encountered error when trying to count API keys: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(keys)
+	log.Debug("200 OK")
+}
+
+// GetAPIKey godoc
+// @Summary      Get API key
+// @Description  Get API key by id, including when it was last used. Admins only.
+// @Tags         API keys
+// @Produce      json
+// @Param   	 apiKeyId      path     int     true  "Id of an API key."
+// @Success      200  {object}  musiclib.APIKey
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys/{apiKeyId} [get]
+func (h *handler) getAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAPIKeyId(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "apiKeyId", Message: "must be a positive integer"})
+		return
+	}
+
+	key, err := h.apiKeys.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrAPIKeyNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(key)
+	log.Debug("200 OK")
+}
+
+// AddAPIKey godoc
+// @Summary      Mint API key
+// @Description  Create an API key for a non-interactive client. Admins only.
+// @Description  The key is part of this response only, store it right away. Clients send it in the X-API-Key header.
+// @Description  Scopes are read:songs and write:songs. A key without read:songs is refused song reads even though anonymous reads are allowed.
+// @Description  A dailyQuota caps the requests of the key per UTC day, further requests get 429 until midnight UTC. 0 means no cap.
+// @Tags         API keys
+// @Accept       json
+// @Param 		 json body musiclib.APIKeyPost true "API key JSON Object"
+// @Produce      json
+// @Success      201  {object}  musiclib.APIKeySecret
+// @Header       201  {string}  Location  "URL of the API key"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys [post]
+func (h *handler) addAPIKey(w http.ResponseWriter, r *http.Request) {
+	var keyPost musiclib.APIKeyPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&keyPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if keyPost.Name == nil || strings.TrimSpace(*keyPost.Name) == "" {
+		log.Debug("400 Bad Request: Invalid Name")
+		problem.Validation(w, r, problem.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+	if len(keyPost.Scopes) == 0 {
+		log.Debug("400 Bad Request: No scopes")
+		problem.Validation(w, r, problem.FieldError{Field: "scopes", Message: "must contain at least one scope"})
+		return
+	}
+	if keyPost.DailyQuota < 0 {
+		log.Debug("400 Bad Request: Invalid DailyQuota")
+		problem.Validation(w, r, problem.FieldError{Field: "dailyQuota", Message: "must not be negative"})
+		return
+	}
+	for i, scope := range keyPost.Scopes {
+		if !scope.Valid() {
+			log.Debug("400 Bad Request: Invalid Scope")
+			problem.Validation(w, r, problem.FieldError{Field: fmt.Sprintf("scopes[%d]", i), Message: "must be read:songs or write:songs"})
+			return
+		}
+	}
+	slices.Sort(keyPost.Scopes)
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		log.Error("Encountered error when trying to generate API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	principal, _ := auth.FromContext(r.Context())
+	key, err := h.apiKeys.Create(r.Context(), musiclib.APIKey{
+		Name:       strings.TrimSpace(*keyPost.Name),
+		Prefix:     prefix,
+		Scopes:     slices.Compact(keyPost.Scopes),
+		CreatedBy:  principal.UserId,
+		DailyQuota: keyPost.DailyQuota,
+		KeyHash:    hash,
+	})
+	if err != nil {
+		log.Error("Encountered error when trying to insert API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/api-keys/%d", key.Id))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(musiclib.APIKeySecret{APIKey: key, Key: secret})
+	log.Debug("201 Created")
+}
+
+// PatchAPIKey godoc
+// @Summary      Patch API key
+// @Description  Rename an API key or change its daily quota, a dailyQuota of 0 removes the cap. Admins only.
+// @Tags         API keys
+// @Accept       json
+// @Param 		 json body string true "API key JSON Object" SchemaExample({"name":"New name", "dailyQuota":5000})
+// @Param   	 apiKeyId      path     int     true  "Id of an API key."
+// @Produce      json
+// @Success      200  {object}  musiclib.APIKey
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys/{apiKeyId} [patch]
+func (h *handler) patchAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAPIKeyId(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "apiKeyId", Message: "must be a positive integer"})
+		return
+	}
+
+	var patchRequest musiclib.APIKeyPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&patchRequest)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if patchRequest.DailyQuota != nil && *patchRequest.DailyQuota < 0 {
+		log.Debug("400 Bad Request: Invalid DailyQuota")
+		problem.Validation(w, r, problem.FieldError{Field: "dailyQuota", Message: "must not be negative"})
+		return
+	}
+
+	key, err := h.apiKeys.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrAPIKeyNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if name := strings.TrimSpace(patchRequest.Name); name != "" {
+		key.Name = name
+	}
+	if patchRequest.DailyQuota != nil {
+		key.DailyQuota = *patchRequest.DailyQuota
+	}
+
+	err = h.apiKeys.Update(r.Context(), key)
+	if errors.Is(err, musiclib.ErrAPIKeyNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to update API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(key)
+	log.Debug("200 OK")
+}
+
+// RotateAPIKey godoc
+// @Summary      Rotate API key
+// @Description  Replace the key of an API key, keeping its id, name and scopes. Admins only. The old key stops working at once, the new one is part of this response only.
+// @Tags         API keys
+// @Produce      json
+// @Param   	 apiKeyId      path     int     true  "Id of an API key."
+// @Success      200  {object}  musiclib.APIKeySecret
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys/{apiKeyId}/rotate [post]
+func (h *handler) rotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAPIKeyId(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "apiKeyId", Message: "must be a positive integer"})
+		return
+	}
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		log.Error("Encountered error when trying to generate API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	key, err := h.apiKeys.Rotate(r.Context(), id, prefix, hash)
+	if errors.Is(err, musiclib.ErrAPIKeyNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to rotate API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.Encode(musiclib.APIKeySecret{APIKey: key, Key: secret})
+	log.Debug("200 OK")
+}
+
+// DeleteAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Delete API key specified by id, it stops working at once. Admins only.
+// @Tags         API keys
+// @Param   	 apiKeyId      path     int     true  "Id of an API key."
+// @Success      204  "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/api-keys/{apiKeyId} [delete]
+func (h *handler) deleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseAPIKeyId(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "apiKeyId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.apiKeys.Delete(r.Context(), id)
+	if err != nil {
+		log.Error("Encountered error when trying to delete API key: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.WriteHeader(204)
+	log.Debug("204 No Content")
+}
+
+func parseAPIKeyId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("api key id must be positive")
+	}
+	return id, nil
+}
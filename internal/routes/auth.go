@@ -0,0 +1,313 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// dummyPasswordHash is compared against on logins of unknown users, so they take
+// as long as logins with a wrong password.
+var dummyPasswordHash, _ = auth.HashPassword("dummy password")
+
+// Register godoc
+// @Summary      Register
+// @Description  Create a user account. Usernames are 3 to 64 characters and unique regardless of case, passwords are 8 to 72 bytes. New accounts are viewers, the admin account is set up from ADMINUSERNAME and ADMINPASSWORD at startup.
+// @Tags         Auth
+// @Accept       json
+// @Param 		 json body musiclib.Credentials true "Credentials JSON Object"
+// @Produce      json
+// @Success      201  {object}  musiclib.User
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/auth/register [post]
+func (h *handler) register(w http.ResponseWriter, r *http.Request) {
+	credentials, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+	username := strings.TrimSpace(credentials.Username)
+	if n := utf8.RuneCountInString(username); n < 3 || n > 64 {
+		log.Debug("400 Bad Request: Invalid Username")
+		problem.Validation(w, r, problem.FieldError{Field: "username", Message: "must be 3 to 64 characters"})
+		return
+	}
+	if utf8.RuneCountInString(credentials.Password) < 8 || len(credentials.Password) > 72 {
+		log.Debug("400 Bad Request: Invalid Password")
+		problem.Validation(w, r, problem.FieldError{Field: "password", Message: "must be 8 to 72 bytes"})
+		return
+	}
+
+	hash, err := auth.HashPassword(credentials.Password)
+	if err != nil {
+		log.Error("Encountered error when trying to hash password: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	user, err := h.users.Create(r.Context(), musiclib.User{Username: username, Role: musiclib.RoleViewer, PasswordHash: hash})
+	if errors.Is(err, musiclib.ErrUserConflict) {
+		log.Debug("409 Conflict: User already exists")
+		problem.Error(w, r, 409, "User already exists")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to insert user: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/auth/me")
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(user)
+	log.Debug("201 Created")
+}
+
+// Login godoc
+// @Summary      Login
+// @Description  Exchange username and password for an access token and a refresh token.
+// @Tags         Auth
+// @Accept       json
+// @Param 		 json body musiclib.Credentials true "Credentials JSON Object"
+// @Produce      json
+// @Success      200  {object}  musiclib.TokenPair
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/auth/login [post]
+func (h *handler) login(w http.ResponseWriter, r *http.Request) {
+	credentials, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.users.GetByName(r.Context(), strings.TrimSpace(credentials.Username))
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		auth.CheckPassword(dummyPasswordHash, credentials.Password)
+		log.Debug("401 Unauthorized: Unknown user")
+		problem.Error(w, r, 401, "Invalid username or password")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get user: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if !auth.CheckPassword(user.PasswordHash, credentials.Password) {
+		log.Debug("401 Unauthorized: Wrong password")
+		problem.Error(w, r, 401, "Invalid username or password")
+		return
+	}
+
+	h.writeTokenPair(w, r, user)
+}
+
+// Refresh godoc
+// @Summary      Refresh
+// @Description  Exchange a refresh token for a new token pair. Every refresh token works once, the response carries its replacement.
+// @Tags         Auth
+// @Accept       json
+// @Param 		 json body musiclib.RefreshRequest true "Refresh JSON Object"
+// @Produce      json
+// @Success      200  {object}  musiclib.TokenPair
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/auth/refresh [post]
+func (h *handler) refresh(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeRefreshRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userId, err := h.users.UseRefreshToken(r.Context(), auth.HashRefreshToken(request.RefreshToken))
+	if errors.Is(err, musiclib.ErrTokenInvalid) {
+		log.Debug("401 Unauthorized: Invalid refresh token")
+		problem.Error(w, r, 401, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to use refresh token: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	user, err := h.users.Get(r.Context(), userId)
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		log.Debug("401 Unauthorized: User is gone")
+		problem.Error(w, r, 401, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get user: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	h.writeTokenPair(w, r, user)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke a refresh token. Access tokens stay valid until they expire.
+// @Tags         Auth
+// @Accept       json
+// @Param 		 json body musiclib.RefreshRequest true "Refresh JSON Object"
+// @Success      204  "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/auth/logout [post]
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeRefreshRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.users.RevokeRefreshToken(r.Context(), auth.HashRefreshToken(request.RefreshToken))
+	if err != nil {
+		log.Error("Encountered error when trying to revoke refresh token: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.WriteHeader(204)
+	log.Debug("204 No Content")
+}
+
+// GetMe godoc
+// @Summary      Current user
+// @Description  Get the user the access token was issued to.
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  musiclib.User
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/auth/me [get]
+func (h *handler) getMe(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.FromContext(r.Context())
+	user, err := h.users.Get(r.Context(), principal.UserId)
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		auth.Unauthorized(w, r, "invalid_token")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get user: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(user)
+	log.Debug("200 OK")
+}
+
+// writeTokenPair issues a new access and refresh token to the user.
+func (h *handler) writeTokenPair(w http.ResponseWriter, r *http.Request, user musiclib.User) {
+	accessToken, err := h.auth.IssueAccessToken(user)
+	if err != nil {
+		log.Error("Encountered error when trying to issue access token: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err == nil {
+		err = h.users.SaveRefreshToken(r.Context(), user.Id, hash, time.Now().Add(h.auth.RefreshTTL()))
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to issue refresh token: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.Encode(musiclib.TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.auth.AccessTTL().Seconds()),
+		RefreshToken: refreshToken,
+	})
+	log.Debug("200 OK")
+}
+
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (musiclib.Credentials, bool) {
+	var credentials musiclib.Credentials
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&credentials)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return credentials, false
+	}
+	if credentials.Username == "" || credentials.Password == "" {
+		log.Debug("400 Bad Request: Missing credentials")
+		var errs []problem.FieldError
+		if credentials.Username == "" {
+			errs = append(errs, problem.FieldError{Field: "username", Message: "is required"})
+		}
+		if credentials.Password == "" {
+			errs = append(errs, problem.FieldError{Field: "password", Message: "is required"})
+		}
+		problem.Validation(w, r, errs...)
+		return credentials, false
+	}
+	return credentials, true
+}
+
+func decodeRefreshRequest(w http.ResponseWriter, r *http.Request) (musiclib.RefreshRequest, bool) {
+	var request musiclib.RefreshRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&request)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return request, false
+	}
+	if request.RefreshToken == "" {
+		log.Debug("400 Bad Request: Missing refresh token")
+		problem.Validation(w, r, problem.FieldError{Field: "refreshToken", Message: "is required"})
+		return request, false
+	}
+	return request, true
+}
+
+// require enforces the permission when authentication is configured and lets
+// everything through otherwise.
+func (h *handler) require(permission auth.Permission) func(http.Handler) http.Handler {
+	if h.auth == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return auth.RequirePermission(permission)
+}
+
+// check rejects authenticated requests lacking the permission when authentication
+// is configured, anonymous requests always pass.
+func (h *handler) check(permission auth.Permission) func(http.Handler) http.Handler {
+	if h.auth == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return auth.CheckPermission(permission)
+}
+
+// authenticate runs the API key and bearer token middlewares of the configured
+// authenticator.
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	if h.auth == nil {
+		return next
+	}
+	next = h.auth.Authenticate(next)
+	if h.apiKeys != nil {
+		next = auth.AuthenticateAPIKey(h.apiKeys)(next)
+	}
+	return next
+}
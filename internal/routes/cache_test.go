@@ -0,0 +1,101 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lynxbites/musiclib/internal/httpcache"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+func TestGetSongNotModified(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/1", "")
+	expectStatus(t, w, 200)
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q, want both", etag, lastModified)
+	}
+
+	w = serve(t, router, "GET", "/api/v1/songs/1", "", "If-None-Match", etag)
+	expectStatus(t, w, 304)
+	if w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("304 has body %q and ETag %q, want no body and %q", w.Body, w.Header().Get("ETag"), etag)
+	}
+	w = serve(t, router, "GET", "/api/v1/songs/1", "", "If-Modified-Since", lastModified)
+	expectStatus(t, w, 304)
+
+	// A change makes both validators stale.
+	time.Sleep(time.Second)
+	w = serve(t, router, "PATCH", "/api/v1/songs/1", `{"text":"Glaciers melting"}`)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", "/api/v1/songs/1", "", "If-None-Match", etag)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", "/api/v1/songs/1", "", "If-Modified-Since", lastModified)
+	expectStatus(t, w, 200)
+}
+
+func TestListSongsNotModified(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/?sort=name", "")
+	expectStatus(t, w, 200)
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q, want only an ETag", etag, w.Header().Get("Last-Modified"))
+	}
+	w = serve(t, router, "GET", "/api/v1/songs/?sort=name", "", "If-None-Match", etag)
+	expectStatus(t, w, 304)
+
+	w = serve(t, router, "DELETE", "/api/v1/songs/2", "")
+	expectStatus(t, w, 204)
+	w = serve(t, router, "GET", "/api/v1/songs/?sort=name", "", "If-None-Match", etag)
+	expectStatus(t, w, 200)
+}
+
+func TestListSongsETagCoversHeaders(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	// Past the last page the body stays [] while the total and the links change.
+	target := "/api/v1/songs/?items=1&page=9"
+	w := serve(t, router, "GET", target, "")
+	expectStatus(t, w, 200)
+	etag := w.Header().Get("ETag")
+
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":"Nirvana","name":"Lithium","releaseDate":"","text":"","link":""}`)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", target, "", "If-None-Match", etag)
+	expectStatus(t, w, 200)
+	if w.Body.String() != "[]\n" || w.Header().Get("ETag") == etag {
+		t.Errorf("body = %q, ETag = %q, want [] under a new ETag", w.Body, w.Header().Get("ETag"))
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	router := newRouter(routes.Services{CacheControl: httpcache.Config{
+		Default: "no-cache",
+		Routes:  map[string]string{"GET /api/v1/songs/{songId}": "private, max-age=60"},
+	}})
+
+	tests := []struct {
+		method, target, want string
+	}{
+		{"GET", "/api/v1/songs/1", "private, max-age=60"},
+		{"GET", "/api/v1/songs/", "no-cache"},
+	}
+	for _, tt := range tests {
+		w := serve(t, router, tt.method, tt.target, "")
+		expectStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("Cache-Control of %s %s = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+
+	w := serve(t, router, "GET", "/api/v1/songs/1", "", "If-None-Match", "*")
+	expectStatus(t, w, 304)
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("Cache-Control of 304 = %q, want the route value", got)
+	}
+}
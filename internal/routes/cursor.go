@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lynxbites/musiclib"
+)
+
+// songCursor is the payload of the opaque cursor token, it pins the sort order the
+// token was issued for and the sort key values of the last song on the page.
+type songCursor struct {
+	Sort  string   `json:"s"`
+	After []string `json:"a"`
+}
+
+// formatSort renders sort keys back into the "field,-field" form.
+func formatSort(sorts []musiclib.SongSort) string {
+	keys := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		key := sort.Field
+		if sort.Desc {
+			key = "-" + key
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// encodeCursor builds the token pointing right after the given song.
+func encodeCursor(sorts []musiclib.SongSort, last musiclib.Song) string {
+	keys := musiclib.NormalizeSort(sorts)
+	cursor := songCursor{Sort: formatSort(keys)}
+	for _, key := range keys {
+		cursor.After = append(cursor.After, last.SortValue(key.Field))
+	}
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// decodeCursor validates a token against the requested sort order and returns the keyset position.
+func decodeCursor(token string, sorts []musiclib.SongSort) ([]string, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("is malformed")
+	}
+	var cursor songCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, errors.New("is malformed")
+	}
+
+	keys := musiclib.NormalizeSort(sorts)
+	if cursor.Sort != formatSort(keys) {
+		return nil, errors.New("was issued for a different sort order")
+	}
+	if len(cursor.After) != len(keys) {
+		return nil, errors.New("is malformed")
+	}
+	for i, key := range keys {
+		if key.Field != musiclib.SortByDate {
+			continue
+		}
+		if _, err := musiclib.ParseReleaseDate(cursor.After[i]); err != nil {
+			return nil, errors.New("is malformed")
+		}
+	}
+	id, err := strconv.Atoi(cursor.After[len(cursor.After)-1])
+	if err != nil || id <= 0 {
+		return nil, errors.New("is malformed")
+	}
+	return cursor.After, nil
+}
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	sorts := []musiclib.SongSort{{Field: musiclib.SortByDate, Desc: true}, {Field: musiclib.SortByName}}
+	last := musiclib.Song{
+		Id:          "7",
+		Name:        "Blue Moon",
+		ReleaseDate: musiclib.ReleaseDate{Year: 1961, Precision: "year"},
+	}
+
+	after, err := decodeCursor(encodeCursor(sorts, last), sorts)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if want := []string{"1961", "Blue Moon", "7"}; !slices.Equal(after, want) {
+		t.Errorf("after = %q, want %q", after, want)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	byName := []musiclib.SongSort{{Field: musiclib.SortByName}}
+	byDate := []musiclib.SongSort{{Field: musiclib.SortByDate}}
+	token := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		sorts []musiclib.SongSort
+		want  string
+	}{
+		{"not base64", "!!!", byName, "is malformed"},
+		{"not JSON", token("cursor"), byName, "is malformed"},
+		{"other sort", encodeCursor(byName, musiclib.Song{Id: "1", Name: "a"}), byDate, "was issued for a different sort order"},
+		{"other direction", encodeCursor(byName, musiclib.Song{Id: "1", Name: "a"}), []musiclib.SongSort{{Field: musiclib.SortByName, Desc: true}}, "was issued for a different sort order"},
+		{"missing values", token(`{"s":"name,id","a":["a"]}`), byName, "is malformed"},
+		{"bad date", token(`{"s":"date,id","a":["soon","1"]}`), byDate, "is malformed"},
+		{"bad id", token(`{"s":"name,id","a":["a","0"]}`), byName, "is malformed"},
+	}
+	for _, tt := range tests {
+		_, err := decodeCursor(tt.token, tt.sorts)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
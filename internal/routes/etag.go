@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/httpcache"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// songETag is the strong entity tag of a song version, versions are never reused so
+// the tag of one song cannot match another or an earlier song under the same id.
+func songETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseIfMatch returns the versions of the strong tags in an If-Match list and whether
+// it is "*". Weak and malformed tags never match under the strong comparison If-Match
+// uses, so they are left out.
+func parseIfMatch(header string) (versions []int, any bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if !strings.HasPrefix(tag, `"`) {
+			continue
+		}
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			continue
+		}
+		version, err := strconv.Atoi(unquoted)
+		if err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, false
+}
+
+// songVersions returns the versions a write of the song is conditional on, see
+// writeVersions. If-Match gives the versions of its tags or musiclib.AnyVersion for "*",
+// and on PUT If-None-Match: "*" gives musiclib.NoVersion, so the song is only created.
+// Without either header the write is unconditional unless the server requires If-Match.
+// A response has been written when ok is false.
+func (h *handler) songVersions(w http.ResponseWriter, r *http.Request) (versions []int, ok bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" && r.Method == http.MethodPut && strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+		return []int{musiclib.NoVersion}, true
+	}
+	if ifMatch == "" {
+		if h.requireIfMatch {
+			log.Debug("428 Precondition Required")
+			problem.Error(w, r, 428, "If-Match with the ETag of the song is required, get the song for its current ETag")
+			return nil, false
+		}
+		return []int{musiclib.AnyVersion}, true
+	}
+	versions, any := parseIfMatch(ifMatch)
+	if any {
+		return []int{musiclib.AnyVersion}, true
+	}
+	return versions, true
+}
+
+// writeVersions runs write with each version until one is not a mismatch. Every try
+// checks its version atomically, so the write happens only while the song is at one of
+// them. Without versions nothing can match and the result is ErrVersionMismatch.
+func writeVersions(versions []int, write func(version int) error) error {
+	err := musiclib.ErrVersionMismatch
+	for _, version := range versions {
+		err = write(version)
+		if !errors.Is(err, musiclib.ErrVersionMismatch) {
+			return err
+		}
+	}
+	return err
+}
+
+// writeStaleSong answers a write whose version did not match with 412 and the current song.
+func (h *handler) writeStaleSong(w http.ResponseWriter, r *http.Request, id int) {
+	song, err := h.songs.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrNotFound) {
+		log.Debug("412 Precondition Failed: Song does not exist")
+		problem.Error(w, r, 412, "The song does not exist")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get song: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("412 Precondition Failed")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", songETag(song.Version))
+	w.WriteHeader(412)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(song)
+}
+
+// notModified sets the ETag, Last-Modified and Cache-Control of a read and answers it
+// with 304 when the client already has this representation.
+func (h *handler) notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	httpcache.SetHeaders(w, etag, lastModified, h.cache.CacheControl(r))
+	if !httpcache.NotModified(r, etag, lastModified) {
+		return false
+	}
+	log.Debug("304 Not Modified")
+	w.WriteHeader(304)
+	return true
+}
+
+// writeSongs writes a page of songs, body holds songs in whatever envelope. Its ETag is
+// a hash of the body and of the X-Total-Count and Link headers already set, so it changes
+// whenever the page or the paging around it does, deleting a song included. There is no
+// Last-Modified, no write time of the page songs tells about deletes.
+func (h *handler) writeSongs(w http.ResponseWriter, r *http.Request, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Error("Encountered error when trying to encode songs: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	data = append(data, '\n')
+	hash := sha256.New()
+	hash.Write(data)
+	hash.Write([]byte(w.Header().Get("X-Total-Count") + "\n" + w.Header().Get("Link")))
+	sum := hash.Sum(nil)
+
+	if h.notModified(w, r, strconv.Quote(hex.EncodeToString(sum[:16])), time.Time{}) {
+		return
+	}
+	w.Write(data)
+	log.Debug("200 OK")
+}
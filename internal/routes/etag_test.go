@@ -0,0 +1,82 @@
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+func TestIfMatch(t *testing.T) {
+	router := newRouter(routes.Services{RequireIfMatch: true})
+
+	w := serve(t, router, "GET", "/api/v1/songs/2", "")
+	expectStatus(t, w, 200)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag is missing")
+	}
+
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon"}`)
+	expectStatus(t, w, 428)
+
+	// Weak tags never match under the strong comparison.
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon"}`, "If-Match", "W/"+etag)
+	expectStatus(t, w, 412)
+	if got := w.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag = %q, want the current %q", got, etag)
+	}
+
+	// Any tag of the list may match.
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"You saw me standing alone"}`, "If-Match", `"999", `+etag)
+	expectStatus(t, w, 200)
+	patched := w.Header().Get("ETag")
+	if patched == etag {
+		t.Fatalf("ETag = %q after the patch, want a new one", patched)
+	}
+
+	w = serve(t, router, "DELETE", "/api/v1/songs/2", "", "If-Match", etag)
+	expectStatus(t, w, 412)
+	w = serve(t, router, "DELETE", "/api/v1/songs/2", "", "If-Match", `"999", `+patched)
+	expectStatus(t, w, 204)
+
+	w = serve(t, router, "PUT", "/api/v1/songs/3", `{"group":"Queen","name":"Bohemian Rhapsody","releaseDate":"1975","text":"","link":""}`, "If-Match", "*")
+	expectStatus(t, w, 200)
+}
+
+func TestStaleETag(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/2", "")
+	etag := w.Header().Get("ETag")
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon, you saw me"}`, "If-Match", etag)
+	expectStatus(t, w, 200)
+
+	// A write based on the old version gets the current song back.
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon, now I'm no longer alone"}`, "If-Match", etag)
+	expectStatus(t, w, 412)
+	if song := decode[musiclib.Song](t, w); song.Text != "Blue moon, you saw me" {
+		t.Fatalf("song = %+v, want the current text", song)
+	}
+	w = serve(t, router, "PUT", "/api/v1/songs/2", `{"group":"Frank Sinatra","name":"Blue Moon","releaseDate":"1961","text":"","link":""}`, "If-Match", etag)
+	expectStatus(t, w, 412)
+}
+
+func TestSongETagNotReused(t *testing.T) {
+	router := newRouter(routes.Services{PutCreates: true})
+	body := `{"group":"Queen","name":"Bohemian Rhapsody","releaseDate":"1975","text":"","link":""}`
+
+	w := serve(t, router, "GET", "/api/v1/songs/3", "")
+	etag := w.Header().Get("ETag")
+	w = serve(t, router, "DELETE", "/api/v1/songs/3", "", "If-Match", etag)
+	expectStatus(t, w, 204)
+	w = serve(t, router, "PUT", "/api/v1/songs/3", body, "If-None-Match", "*")
+	expectStatus(t, w, 201)
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Fatalf("ETag = %q, want the recreated song to have a new one", got)
+	}
+
+	// The tag of the deleted song does not match the new song under its id.
+	w = serve(t, router, "PUT", "/api/v1/songs/3", body, "If-Match", etag)
+	expectStatus(t, w, 412)
+}
@@ -0,0 +1,316 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetGroupList godoc
+// @Summary      Get groups
+// @Description  Gets list of groups ordered by name, with pagination.
+// @Tags         Groups
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.Group "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of groups"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/groups [get]
+func (h *handler) getGroupList(w http.ResponseWriter, r *http.Request) {
+
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	groups, err := h.groups.List(r.Context(), items, (page-1)*items)
+	if err != nil {
+		log.Error("Encountered error when trying to get group list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if groups == nil {
+		groups = []musiclib.Group{}
+	}
+
+	total, err := h.groups.Count(r.Context())
+	if err != nil {
+		log.Error("Encountered error when trying to count groups: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(groups)
+	log.Debug("200 OK")
+}
+
+// GetGroup godoc
+// @Summary      Get group
+// @Description  Get group by id.
+// @Tags         Groups
+// @Produce      json
+// @Param   	 groupId      path     int     true  "Id of a group."
+// @Success      200  {object}  musiclib.Group
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/groups/{groupId} [get]
+func (h *handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGroupId(chi.URLParam(r, "groupId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+		return
+	}
+
+	group, err := h.groups.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrGroupNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Group not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(group)
+	log.Debug("200 OK")
+}
+
+// GetGroupSongs godoc
+// @Summary      Get group songs
+// @Description  Gets songs of a group. Accepts the same filters, sorting and pagination as Get songs.
+// @Tags         Groups
+// @Param   	 groupId      path     int     true  "Id of a group."
+// @Param   sort      query     string     false  "Comma-separated sort keys out of id, group, name, date, text and link, prefix with - for descending order."
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Param   cursor      query     string     false 	"Keyset pagination token, pass it empty for the first page and then the returned next_cursor."
+// @Param   envelope      query     bool     false 	"Wrap the page into musiclib.SongListPage with total count and paging info."
+// @Produce      json
+// @Success      200 {array} musiclib.Song "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of songs of the group matching the filters"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/groups/{groupId}/songs [get]
+func (h *handler) getGroupSongs(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGroupId(chi.URLParam(r, "groupId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+		return
+	}
+
+	_, err = h.groups.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrGroupNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Group not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	filter, invalid := parseSongFilter(r.URL.Query())
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+	filter.GroupId = id
+	h.listSongs(w, r, filter)
+}
+
+// AddGroup godoc
+// @Summary      Post group
+// @Description  Create a group. Names are unique regardless of case.
+// @Tags         Groups
+// @Accept       json
+// @Param 		 json body string true "Group JSON Object" SchemaExample({"name":"Group name"})
+// @Produce      json
+// @Success      201  {object}  musiclib.Group
+// @Header       201  {string}  Location  "URL of the group"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/groups [post]
+func (h *handler) addGroup(w http.ResponseWriter, r *http.Request) {
+	var groupPost musiclib.GroupPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&groupPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if groupPost.Name == nil || strings.TrimSpace(*groupPost.Name) == "" {
+		log.Debug("400 Bad Request: Invalid Name")
+		problem.Validation(w, r, problem.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+
+	group, err := h.groups.Create(r.Context(), strings.TrimSpace(*groupPost.Name))
+	if errors.Is(err, musiclib.ErrGroupConflict) {
+		log.Debug("409 Conflict: Group already exists")
+		problem.Error(w, r, 409, "Group already exists")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to insert group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/groups/%d", group.Id))
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(group)
+	log.Debug("201 Created")
+}
+
+// PatchGroup godoc
+// @Summary      Patch group
+// @Description  Rename group specified by id, the new name shows up on all of its songs.
+// @Tags         Groups
+// @Produce      json
+// @Param 		 json body string true "Group JSON Object" SchemaExample({"name":"New name"})
+// @Param   	 groupId      path     int     true  "Id of a group to patch."
+// @Success      200  {object}  musiclib.Group
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/groups/{groupId} [patch]
+func (h *handler) patchGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGroupId(chi.URLParam(r, "groupId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+		return
+	}
+
+	var patchRequest musiclib.GroupPatch
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&patchRequest)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	group, err := h.groups.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrGroupNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Group not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	if name := strings.TrimSpace(patchRequest.Name); name != "" {
+		group.Name = name
+	}
+
+	err = h.groups.Update(r.Context(), group)
+	if errors.Is(err, musiclib.ErrGroupNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Group not found")
+		return
+	}
+	if errors.Is(err, musiclib.ErrGroupConflict) {
+		log.Debug("409 Conflict: Group already exists")
+		problem.Error(w, r, 409, "Group already exists")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to update group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(group)
+	log.Debug("200 OK")
+}
+
+// DeleteGroup godoc
+// @Summary      Delete group
+// @Description  Delete a group without songs and albums.
+// @Tags         Groups
+// @Produce      json
+// @Param   	 groupId      path     int     true  "Id of a group to delete"
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      409  {object}  problem.Problem  "Group still has songs or albums"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/groups/{groupId} [delete]
+func (h *handler) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGroupId(chi.URLParam(r, "groupId"))
+	if err != nil {
+		log.Debug("400 Bad request")
+		problem.Validation(w, r, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.groups.Delete(r.Context(), id)
+	if errors.Is(err, musiclib.ErrGroupNotEmpty) {
+		log.Debug("409 Conflict: Group has songs or albums")
+		problem.Error(w, r, 409, "Group has songs or albums")
+		return
+	}
+	if err != nil && !errors.Is(err, musiclib.ErrGroupNotFound) {
+		log.Error("Encountered error when trying to delete group: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// parseGroupId parses a groupId path parameter, only positive integers are valid.
+func parseGroupId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("group id must be positive")
+	}
+	return id, nil
+}
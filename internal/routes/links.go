@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// pageLink is a single RFC 8288 link relation.
+type pageLink struct {
+	rel    string
+	params map[string]string
+}
+
+// setLinkHeader writes the Link header, each link is the request URL with params replaced.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, links []pageLink) {
+	var values []string
+	for _, link := range links {
+		q := r.URL.Query()
+		for key, value := range link.params {
+			q.Set(key, value)
+		}
+		u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), link.rel))
+	}
+	if len(values) > 0 {
+		w.Header().Set("Link", strings.Join(values, ", "))
+	}
+}
+
+// offsetLinks returns first/prev/next/last links for page-numbered lists.
+func offsetLinks(page, lastPage int) []pageLink {
+	pageParams := func(n int) map[string]string {
+		return map[string]string{"page": strconv.Itoa(n)}
+	}
+	links := []pageLink{{rel: "first", params: pageParams(1)}}
+	if page > 1 {
+		links = append(links, pageLink{rel: "prev", params: pageParams(min(page-1, lastPage))})
+	}
+	if page < lastPage {
+		links = append(links, pageLink{rel: "next", params: pageParams(page + 1)})
+	}
+	return append(links, pageLink{rel: "last", params: pageParams(lastPage)})
+}
+
+// verseLinks returns first/prev/next/last links for the offset/limit verse pagination.
+func verseLinks(offset, limit, total int) []pageLink {
+	verseParams := func(n int) map[string]string {
+		return map[string]string{"offset": strconv.Itoa(n), "limit": strconv.Itoa(limit)}
+	}
+	last := 0
+	if total > 0 {
+		last = ((total - 1) / limit) * limit
+	}
+	links := []pageLink{{rel: "first", params: verseParams(0)}}
+	if offset > 0 {
+		links = append(links, pageLink{rel: "prev", params: verseParams(min(max(offset-limit, 0), last))})
+	}
+	if offset+limit < total {
+		links = append(links, pageLink{rel: "next", params: verseParams(offset + limit)})
+	}
+	return append(links, pageLink{rel: "last", params: verseParams(last)})
+}
@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"maps"
+	"net/http/httptest"
+	"testing"
+)
+
+// linkParams maps each relation to the given parameter of its link.
+func linkParams(links []pageLink, param string) map[string]string {
+	params := make(map[string]string, len(links))
+	for _, link := range links {
+		params[link.rel] = link.params[param]
+	}
+	return params
+}
+
+func TestOffsetLinks(t *testing.T) {
+	tests := []struct {
+		page, lastPage int
+		want           map[string]string
+	}{
+		{1, 1, map[string]string{"first": "1", "last": "1"}},
+		{1, 3, map[string]string{"first": "1", "next": "2", "last": "3"}},
+		{2, 3, map[string]string{"first": "1", "prev": "1", "next": "3", "last": "3"}},
+		{3, 3, map[string]string{"first": "1", "prev": "2", "last": "3"}},
+		// Past the end, prev leads back to the last page.
+		{7, 3, map[string]string{"first": "1", "prev": "3", "last": "3"}},
+	}
+	for _, tt := range tests {
+		if got := linkParams(offsetLinks(tt.page, tt.lastPage), "page"); !maps.Equal(got, tt.want) {
+			t.Errorf("offsetLinks(%d, %d) = %v, want %v", tt.page, tt.lastPage, got, tt.want)
+		}
+	}
+}
+
+func TestVerseLinks(t *testing.T) {
+	tests := []struct {
+		offset, limit, total int
+		want                 map[string]string
+	}{
+		{0, 3, 0, map[string]string{"first": "0", "last": "0"}},
+		{0, 3, 7, map[string]string{"first": "0", "next": "3", "last": "6"}},
+		{3, 3, 7, map[string]string{"first": "0", "prev": "0", "next": "6", "last": "6"}},
+		{6, 3, 7, map[string]string{"first": "0", "prev": "3", "last": "6"}},
+		{1, 3, 6, map[string]string{"first": "0", "prev": "0", "next": "4", "last": "3"}},
+		{10, 3, 7, map[string]string{"first": "0", "prev": "6", "last": "6"}},
+	}
+	for _, tt := range tests {
+		links := verseLinks(tt.offset, tt.limit, tt.total)
+		if got := linkParams(links, "offset"); !maps.Equal(got, tt.want) {
+			t.Errorf("verseLinks(%d, %d, %d) = %v, want %v", tt.offset, tt.limit, tt.total, got, tt.want)
+		}
+		for _, link := range links {
+			if link.params["limit"] != "3" {
+				t.Errorf("verseLinks(%d, %d, %d) %s limit = %q, want 3", tt.offset, tt.limit, tt.total, link.rel, link.params["limit"])
+			}
+		}
+	}
+}
+
+func TestSetLinkHeader(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/songs/?sort=name&page=2", nil)
+	w := httptest.NewRecorder()
+	setLinkHeader(w, r, offsetLinks(2, 2))
+
+	want := `</api/v1/songs/?page=1&sort=name>; rel="first", </api/v1/songs/?page=1&sort=name>; rel="prev", </api/v1/songs/?page=2&sort=name>; rel="last"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link = %s, want %s", got, want)
+	}
+}
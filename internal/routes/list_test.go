@@ -0,0 +1,134 @@
+package routes_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+// names returns the song names of a list response in order.
+func names(songs []musiclib.Song) []string {
+	names := make([]string, len(songs))
+	for i, song := range songs {
+		names[i] = song.Name
+	}
+	return names
+}
+
+func TestListSongsSortAndPage(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/v1/songs/?items=2&sort=name", []string{"Blue Moon", "Bohemian Rhapsody"}},
+		{"/api/v1/songs/?items=2&page=2&sort=name", []string{"Supermassive Black Hole"}},
+		{"/api/v1/songs/?items=3&sort=-date", []string{"Supermassive Black Hole", "Bohemian Rhapsody", "Blue Moon"}},
+		{"/api/v1/songs/?items=3&sort=group,-name", []string{"Blue Moon", "Supermassive Black Hole", "Bohemian Rhapsody"}},
+		{"/api/v1/songs/?items=3&filter=group", []string{"Blue Moon", "Supermassive Black Hole", "Bohemian Rhapsody"}},
+		{"/api/v1/songs/?items=2&page=3", []string{}},
+	}
+	for _, tt := range tests {
+		w := serve(t, router, "GET", tt.target, "")
+		expectStatus(t, w, 200)
+		got := names(decode[[]musiclib.Song](t, w))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestListSongsFilters(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"name=Blue+Moon", []string{"Blue Moon"}},
+		{"namePrefix=B", []string{"Blue Moon", "Bohemian Rhapsody"}},
+		{"nameContains=HOLE", []string{"Supermassive Black Hole"}},
+		{"groupPrefix=Q", []string{"Bohemian Rhapsody"}},
+		{"groupContains=sinatra", []string{"Blue Moon"}},
+		{"releaseDateFrom=1970&releaseDateTo=1999", []string{"Bohemian Rhapsody"}},
+		{"releaseDateFrom=2006-06", []string{"Supermassive Black Hole"}},
+		{"linkHost=example.com", []string{"Supermassive Black Hole"}},
+		{"textContains=moon", []string{"Blue Moon"}},
+		{"groupPrefix=M&namePrefix=B", []string{}},
+	}
+	for _, tt := range tests {
+		target := "/api/v1/songs/?items=10&sort=name&" + tt.query
+		w := serve(t, router, "GET", target, "")
+		expectStatus(t, w, 200)
+		if got := names(decode[[]musiclib.Song](t, w)); !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s = %v, want %v", target, got, tt.want)
+		}
+	}
+}
+
+func TestListSongsInvalidPage(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/?page=abc&items=0", "")
+	expectFields(t, w, "page", "items")
+	w = serve(t, router, "GET", "/api/v1/songs/?page=-1", "")
+	expectFields(t, w, "page")
+	w = serve(t, router, "GET", "/api/v1/songs/?items=2.5", "")
+	expectFields(t, w, "items")
+}
+
+func TestListSongsCursor(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	var got []string
+	target := "/api/v1/songs/?items=2&sort=name&cursor="
+	for range 3 {
+		w := serve(t, router, "GET", target, "")
+		expectStatus(t, w, 200)
+		page := decode[musiclib.SongCursorPage](t, w)
+		got = append(got, names(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		target = "/api/v1/songs/?items=2&sort=name&cursor=" + page.NextCursor
+	}
+	if want := []string{"Blue Moon", "Bohemian Rhapsody", "Supermassive Black Hole"}; !slices.Equal(got, want) {
+		t.Fatalf("songs = %v, want %v", got, want)
+	}
+
+	w := serve(t, router, "GET", "/api/v1/songs/?cursor=&sort=text", "")
+	expectFields(t, w, "sort")
+	w = serve(t, router, "GET", "/api/v1/songs/?cursor=&filter=link", "")
+	expectFields(t, w, "filter")
+	w = serve(t, router, "GET", "/api/v1/songs/?cursor=&page=2", "")
+	expectFields(t, w, "page")
+	w = serve(t, router, "GET", "/api/v1/songs/?cursor=garbage", "")
+	expectFields(t, w, "cursor")
+}
+
+func TestListSongsEnvelope(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/?items=2&sort=name&envelope=true", "")
+	expectStatus(t, w, 200)
+	page := decode[musiclib.SongListPage](t, w)
+	if page.Total != 3 || page.Page != 1 || page.PageSize != 2 || !page.HasNext || len(page.Items) != 2 {
+		t.Fatalf("page = %+v, want 2 of 3 songs with a next page", page)
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "3" {
+		t.Errorf("X-Total-Count = %q, want 3", got)
+	}
+	wantLink := `</api/v1/songs/?envelope=true&items=2&page=1&sort=name>; rel="first", </api/v1/songs/?envelope=true&items=2&page=2&sort=name>; rel="next", </api/v1/songs/?envelope=true&items=2&page=2&sort=name>; rel="last"`
+	if got := w.Header().Get("Link"); got != wantLink {
+		t.Errorf("Link = %s, want %s", got, wantLink)
+	}
+
+	w = serve(t, router, "GET", "/api/v1/songs/?items=2&page=2&sort=name&envelope=true", "")
+	expectStatus(t, w, 200)
+	if page := decode[musiclib.SongListPage](t, w); page.HasNext || len(page.Items) != 1 {
+		t.Fatalf("page = %+v, want the last song without a next page", page)
+	}
+}
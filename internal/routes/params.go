@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// parseSongFilter reads the song list filters from the query string and reports every
+// invalid parameter.
+func parseSongFilter(q url.Values) (musiclib.SongFilter, []problem.FieldError) {
+	var errs []problem.FieldError
+	releaseDateFrom, err := musiclib.ParseReleaseDate(q.Get("releaseDateFrom"))
+	if err != nil {
+		errs = append(errs, problem.FieldError{Field: "releaseDateFrom", Message: musiclib.IsReleaseDate(q.Get("releaseDateFrom"))})
+	}
+	releaseDateTo, err := musiclib.ParseReleaseDate(q.Get("releaseDateTo"))
+	if err != nil {
+		errs = append(errs, problem.FieldError{Field: "releaseDateTo", Message: musiclib.IsReleaseDate(q.Get("releaseDateTo"))})
+	}
+	var groupId int
+	if param := q.Get("groupId"); param != "" {
+		groupId, err = strconv.Atoi(param)
+		if err != nil || groupId <= 0 {
+			errs = append(errs, problem.FieldError{Field: "groupId", Message: "must be a positive integer"})
+		}
+	}
+	if len(errs) > 0 {
+		return musiclib.SongFilter{}, errs
+	}
+
+	return musiclib.SongFilter{
+		GroupId:         groupId,
+		Group:           q.Get("group"),
+		GroupPrefix:     q.Get("groupPrefix"),
+		GroupContains:   q.Get("groupContains"),
+		GroupFuzzy:      q.Get("groupFuzzy"),
+		Name:            q.Get("name"),
+		NamePrefix:      q.Get("namePrefix"),
+		NameContains:    q.Get("nameContains"),
+		NameFuzzy:       q.Get("nameFuzzy"),
+		ReleaseDateFrom: releaseDateFrom,
+		ReleaseDateTo:   releaseDateTo,
+		LinkHost:        q.Get("linkHost"),
+		TextContains:    q.Get("textContains"),
+	}, nil
+}
+
+// parseSort parses a "field,-field" sort parameter, a leading "-" means descending order.
+func parseSort(param string) ([]musiclib.SongSort, []problem.FieldError) {
+	if param == "" {
+		return nil, nil
+	}
+	var sorts []musiclib.SongSort
+	for _, key := range strings.Split(param, ",") {
+		key = strings.TrimSpace(key)
+		var sort musiclib.SongSort
+		if strings.HasPrefix(key, "-") {
+			sort.Desc = true
+			key = key[1:]
+		}
+		if key == "releaseDate" {
+			key = musiclib.SortByDate
+		}
+		if !musiclib.IsSortField(key) {
+			return nil, []problem.FieldError{{Field: "sort", Message: fmt.Sprintf("has unknown field %q, use id, group, name, date, text or link", key)}}
+		}
+		sort.Field = key
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
+// parsePage reads the page and items query parameters of offset pagination, page
+// defaults to 1 and items to defaultItems. Both have to be positive integers.
+func parsePage(r *http.Request, defaultItems int) (page, items int, invalid []problem.FieldError) {
+	page, items = 1, defaultItems
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{
+		{"page", &page},
+		{"items", &items},
+	} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			invalid = append(invalid, problem.FieldError{Field: param.name, Message: "must be a positive integer"})
+			continue
+		}
+		*param.dst = n
+	}
+	return page, items, invalid
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query             string
+		page, items       int
+		invalidParameters int
+	}{
+		{"", 1, 5, 0},
+		{"page=3&items=20", 3, 20, 0},
+		{"page=0", 1, 5, 1},
+		{"items=-2", 1, 5, 1},
+		{"page=two&items=x", 1, 5, 2},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/?"+tt.query, nil)
+		page, items, invalid := parsePage(r, 5)
+		if len(invalid) != tt.invalidParameters {
+			t.Errorf("parsePage(%q) invalid = %+v, want %d errors", tt.query, invalid, tt.invalidParameters)
+			continue
+		}
+		if page != tt.page || items != tt.items {
+			t.Errorf("parsePage(%q) = %d, %d, want %d, %d", tt.query, page, items, tt.page, tt.items)
+		}
+	}
+}
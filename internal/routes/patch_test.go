@@ -0,0 +1,60 @@
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+func TestPatchSongMergePatch(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "PATCH", "/api/v1/songs/1", `{"link":null,"text":"Glaciers melting"}`, "Content-Type", "application/merge-patch+json")
+	expectStatus(t, w, 200)
+	song := decode[musiclib.Song](t, w)
+	if song.Link != "" || song.Text != "Glaciers melting" || song.Name != "Supermassive Black Hole" {
+		t.Fatalf("song = %+v, want the link cleared and the text set", song)
+	}
+
+	// Clearing a required field is reported like on post.
+	w = serve(t, router, "PATCH", "/api/v1/songs/1", `{"name":null}`, "Content-Type", "application/merge-patch+json")
+	expectFields(t, w, "name")
+}
+
+func TestPatchSongJSONPatch(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "PATCH", "/api/v1/songs/3", `[{"op":"test","path":"/group","value":"Queen"},{"op":"replace","path":"/releaseDate","value":"1975-10-31"}]`, "Content-Type", "application/json-patch+json")
+	expectStatus(t, w, 200)
+	if song := decode[musiclib.Song](t, w); song.ReleaseDate.String() != "1975-10-31" {
+		t.Fatalf("song = %+v, want the release date replaced", song)
+	}
+
+	// A failed test leaves the song as it was.
+	w = serve(t, router, "PATCH", "/api/v1/songs/3", `[{"op":"replace","path":"/text","value":"Mama"},{"op":"test","path":"/group","value":"Muse"}]`, "Content-Type", "application/json-patch+json")
+	expectStatus(t, w, 409)
+	w = serve(t, router, "GET", "/api/v1/songs/3", "")
+	expectStatus(t, w, 200)
+	if song := decode[musiclib.SongPaginated](t, w); len(song.Text) != 0 {
+		t.Fatalf("verses = %q, want none", song.Text)
+	}
+
+	w = serve(t, router, "PATCH", "/api/v1/songs/3", `[{"op":"move","path":"/text","value":""}]`, "Content-Type", "application/json-patch+json")
+	expectFields(t, w, "[0].op")
+}
+
+func TestPatchSongInvalid(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon","album":"Swing Easy!"}`)
+	expectStatus(t, w, 400)
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"group":"","link":"ftp://example.com"}`)
+	expectFields(t, w, "group", "link")
+
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `text=Blue+moon`, "Content-Type", "application/x-www-form-urlencoded")
+	expectStatus(t, w, 415)
+	if got := w.Header().Get("Accept-Patch"); got != "application/json, application/merge-patch+json, application/json-patch+json" {
+		t.Errorf("Accept-Patch = %q, want the patch formats", got)
+	}
+}
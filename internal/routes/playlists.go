@@ -0,0 +1,458 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/auth"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetPlaylistList godoc
+// @Summary      Get playlists
+// @Description  Gets list of playlists without entries, ordered by name.
+// @Tags         Playlists
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.Playlist "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of playlists"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/playlists [get]
+func (h *handler) getPlaylistList(w http.ResponseWriter, r *http.Request) {
+
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	playlists, err := h.playlists.List(r.Context(), items, (page-1)*items)
+	if err != nil {
+		log.Error("Encountered error when trying to get playlist list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if playlists == nil {
+		playlists = []musiclib.Playlist{}
+	}
+
+	total, err := h.playlists.Count(r.Context())
+	if err != nil {
+		log.Error("Encountered error when trying to count playlists: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlists)
+	log.Debug("200 OK")
+}
+
+// GetPlaylist godoc
+// @Summary      Get playlist
+// @Description  Get playlist by id with its songs expanded in order.
+// @Tags         Playlists
+// @Produce      json
+// @Param   	 playlistId      path     int     true  "Id of a playlist."
+// @Success      200  {object}  musiclib.Playlist
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/playlists/{playlistId} [get]
+func (h *handler) getPlaylist(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+
+	playlist, err := h.playlists.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrPlaylistNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Playlist not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlist)
+	log.Debug("200 OK")
+}
+
+// AddPlaylist godoc
+// @Summary      Post playlist
+// @Description  Create an empty playlist owned by the user creating it.
+// @Tags         Playlists
+// @Accept       json
+// @Param 		 json body string true "Playlist JSON Object" SchemaExample({"name":"Road trip"})
+// @Produce      json
+// @Success      201  {object}  musiclib.Playlist
+// @Header       201  {string}  Location  "URL of the playlist"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists [post]
+func (h *handler) addPlaylist(w http.ResponseWriter, r *http.Request) {
+	var playlistPost musiclib.PlaylistPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&playlistPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if playlistPost.Name == nil || strings.TrimSpace(*playlistPost.Name) == "" {
+		log.Debug("400 Bad Request: Invalid Name")
+		problem.Validation(w, r, problem.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+
+	var ownerId int
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		ownerId = principal.UserId
+	}
+	playlist, err := h.playlists.Create(r.Context(), strings.TrimSpace(*playlistPost.Name), ownerId)
+	if err != nil {
+		log.Error("Encountered error when trying to insert playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/playlists/%d", playlist.Id))
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlist)
+	log.Debug("201 Created")
+}
+
+// PatchPlaylist godoc
+// @Summary      Patch playlist
+// @Description  Rename playlist specified by id. Viewers may only change playlists they created, editors and admins any playlist.
+// @Tags         Playlists
+// @Accept       json
+// @Param 		 json body string true "Playlist JSON Object" SchemaExample({"name":"New name"})
+// @Param   	 playlistId      path     int     true  "Id of a playlist to patch."
+// @Success      200  "OK"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists/{playlistId} [patch]
+func (h *handler) patchPlaylist(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+
+	var patchRequest musiclib.PlaylistPatch
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&patchRequest)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	name := strings.TrimSpace(patchRequest.Name)
+	if name == "" {
+		log.Debug("400 Bad Request: Invalid Name")
+		problem.Validation(w, r, problem.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+
+	err = h.playlists.Update(r.Context(), musiclib.Playlist{Id: id, Name: name})
+	if errors.Is(err, musiclib.ErrPlaylistNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Playlist not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to update playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("200 OK")
+	w.WriteHeader(200)
+}
+
+// DeletePlaylist godoc
+// @Summary      Delete playlist
+// @Description  Delete a playlist, its songs are kept. Viewers and editors may only delete playlists they created, admins any playlist.
+// @Tags         Playlists
+// @Param   	 playlistId      path     int     true  "Id of a playlist to delete"
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists/{playlistId} [delete]
+func (h *handler) deletePlaylist(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.playlists.Delete(r.Context(), id)
+	if err != nil && !errors.Is(err, musiclib.ErrPlaylistNotFound) {
+		log.Error("Encountered error when trying to delete playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// AddPlaylistEntry godoc
+// @Summary      Add playlist entry
+// @Description  Add a song to the playlist at position, counted from 1. The entries from that position on move down by one, without position or past the end the song is appended. A song may be added more than once. Viewers may only change playlists they created, editors and admins any playlist.
+// @Tags         Playlists
+// @Accept       json
+// @Param 		 json body string true "Entry JSON Object" SchemaExample({"songId":1, "position":2})
+// @Param   	 playlistId      path     int     true  "Id of a playlist."
+// @Produce      json
+// @Success      201  {object}  musiclib.PlaylistEntry
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Playlist or song not found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists/{playlistId}/entries [post]
+func (h *handler) addPlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+
+	var entryPost musiclib.PlaylistEntryPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&entryPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if entryPost.SongId <= 0 || entryPost.Position < 0 {
+		log.Debug("400 Bad Request: Invalid entry")
+		var errs []problem.FieldError
+		if entryPost.SongId <= 0 {
+			errs = append(errs, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
+		}
+		if entryPost.Position < 0 {
+			errs = append(errs, problem.FieldError{Field: "position", Message: "must not be negative"})
+		}
+		problem.Validation(w, r, errs...)
+		return
+	}
+
+	entry, err := h.playlists.AddEntry(r.Context(), id, entryPost.SongId, entryPost.Position)
+	if !h.checkEntryError(w, r, err) {
+		return
+	}
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(entry)
+	log.Debug("201 Created")
+}
+
+// MovePlaylistEntry godoc
+// @Summary      Move playlist entry
+// @Description  Move an entry to position, counted from 1 and clamped to the playlist length. The entries in between shift by one. Viewers may only change playlists they created, editors and admins any playlist.
+// @Tags         Playlists
+// @Accept       json
+// @Param 		 json body string true "Entry JSON Object" SchemaExample({"position":1})
+// @Param   	 playlistId      path     int     true  "Id of a playlist."
+// @Param   	 entryId      path     int     true  "Id of a playlist entry."
+// @Success      200  "OK"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Playlist or entry not found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists/{playlistId}/entries/{entryId} [patch]
+func (h *handler) movePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+	entryId, err := parseEntryId(chi.URLParam(r, "entryId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "entryId", Message: "must be a positive integer"})
+		return
+	}
+
+	var entryPatch musiclib.PlaylistEntryPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&entryPatch)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if entryPatch.Position <= 0 {
+		log.Debug("400 Bad Request: Invalid position")
+		problem.Validation(w, r, problem.FieldError{Field: "position", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.playlists.MoveEntry(r.Context(), id, entryId, entryPatch.Position)
+	if !h.checkEntryError(w, r, err) {
+		return
+	}
+	log.Debug("200 OK")
+	w.WriteHeader(200)
+}
+
+// RemovePlaylistEntry godoc
+// @Summary      Remove playlist entry
+// @Description  Remove an entry from the playlist, the following entries move up by one. Viewers may only change playlists they created, editors and admins any playlist.
+// @Tags         Playlists
+// @Param   	 playlistId      path     int     true  "Id of a playlist."
+// @Param   	 entryId      path     int     true  "Id of a playlist entry."
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Playlist or entry not found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/playlists/{playlistId}/entries/{entryId} [delete]
+func (h *handler) removePlaylistEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "playlistId", Message: "must be a positive integer"})
+		return
+	}
+	entryId, err := parseEntryId(chi.URLParam(r, "entryId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "entryId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.playlists.RemoveEntry(r.Context(), id, entryId)
+	if !h.checkEntryError(w, r, err) {
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// ownPlaylist lets only the owner of the playlist and principals with permission
+// change it, others get 403. Invalid ids and missing playlists are left to the handler.
+func (h *handler) ownPlaylist(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if h.auth == nil || !ok || principal.Can(permission) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, err := parsePlaylistId(chi.URLParam(r, "playlistId"))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			playlist, err := h.playlists.Get(r.Context(), id)
+			if errors.Is(err, musiclib.ErrPlaylistNotFound) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.Error("Encountered error when trying to get playlist: %v", err)
+				problem.Internal(w, r)
+				return
+			}
+			if playlist.OwnerId == 0 || playlist.OwnerId != principal.UserId {
+				message := fmt.Sprintf("Only the owner of the playlist or a role with permission %q can change it", permission)
+				log.Debug("403 Forbidden: " + message)
+				problem.Error(w, r, 403, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkEntryError writes the response for a failed playlist entry change and reports
+// whether the change succeeded.
+func (h *handler) checkEntryError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, musiclib.ErrPlaylistNotFound):
+		log.Debug("404 Not Found: Playlist does not exist")
+		problem.Error(w, r, 404, "Playlist not found")
+	case errors.Is(err, musiclib.ErrNotFound):
+		log.Debug("404 Not Found: Song does not exist")
+		problem.Error(w, r, 404, "Song not found")
+	case errors.Is(err, musiclib.ErrEntryNotFound):
+		log.Debug("404 Not Found: Entry does not exist")
+		problem.Error(w, r, 404, "Entry not found")
+	default:
+		log.Error("Encountered error when trying to change playlist entries: %v", err)
+		problem.Internal(w, r)
+	}
+	return false
+}
+
+// parsePlaylistId parses a playlistId path parameter, only positive integers are valid.
+func parsePlaylistId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("playlist id must be positive")
+	}
+	return id, nil
+}
+
+// parseEntryId parses an entryId path parameter, only positive integers are valid.
+func parseEntryId(param string) (int64, error) {
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("entry id must be positive")
+	}
+	return id, nil
+}
@@ -0,0 +1,70 @@
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/lynxbites/musiclib/internal/problem"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+func TestProblemResponses(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/99", "")
+	expectStatus(t, w, 404)
+	if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problem.ContentType)
+	}
+	p := decode[problem.Problem](t, w)
+	if p.Type != "about:blank" || p.Title != "Not Found" || p.Status != 404 || p.Detail != "Song not found" || p.Instance != "/api/v1/songs/99" {
+		t.Errorf("problem = %+v, want a song not found problem", p)
+	}
+	if p.RequestId == "" || p.RequestId != w.Header().Get("X-Request-Id") {
+		t.Errorf("requestId = %q, X-Request-Id = %q, want the same id", p.RequestId, w.Header().Get("X-Request-Id"))
+	}
+
+	w = serve(t, router, "GET", "/api/v1/nothing", "")
+	expectStatus(t, w, 404)
+	if p := decode[problem.Problem](t, w); p.Detail != "No route matches the request path" {
+		t.Errorf("problem = %+v, want the unknown route problem", p)
+	}
+
+	w = serve(t, router, "POST", "/api/v1/songs/1", "")
+	expectStatus(t, w, 405)
+	if got := w.Header().Get("Allow"); got != "GET, PUT, PATCH, DELETE" {
+		t.Errorf("Allow = %q, want the methods of the song route", got)
+	}
+
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":`)
+	expectStatus(t, w, 400)
+	if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, problem.ContentType)
+	}
+}
+
+func TestListSongsInvalidQuery(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	tests := []struct {
+		query  string
+		fields []string
+	}{
+		{"releaseDateFrom=soon&releaseDateTo=later&groupId=x", []string{"releaseDateFrom", "releaseDateTo", "groupId"}},
+		{"groupId=0", []string{"groupId"}},
+		{"sort=rating", []string{"sort"}},
+		{"sort=name,-rating", []string{"sort"}},
+		{"cursor=&sort=text", []string{"sort"}},
+		{"cursor=garbage", []string{"cursor"}},
+		{"cursor=&page=1", []string{"page"}},
+	}
+	for _, tt := range tests {
+		w := serve(t, router, "GET", "/api/v1/songs/?"+tt.query, "")
+		expectFields(t, w, tt.fields...)
+	}
+
+	w := serve(t, router, "GET", "/api/v1/songs/?sort=rating", "")
+	p := decode[problem.Problem](t, w)
+	if want := `has unknown field "rating", use id, group, name, date, text or link`; len(p.Errors) != 1 || p.Errors[0].Message != want {
+		t.Errorf("errors = %+v, want %q", p.Errors, want)
+	}
+}
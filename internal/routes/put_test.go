@@ -0,0 +1,61 @@
+package routes_test
+
+import (
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+const queenPut = `{"group":"Queen","name":"Bohemian Rhapsody","releaseDate":"1975-10-31","text":"Is this the real life?","link":""}`
+
+func TestPutSong(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "PUT", "/api/v1/songs/3", queenPut)
+	expectStatus(t, w, 200)
+	song := decode[musiclib.Song](t, w)
+	if song.Id != "3" || song.ReleaseDate.String() != "1975-10-31" || song.Text != "Is this the real life?" {
+		t.Fatalf("song = %+v, want the replaced Queen song", song)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("ETag is missing")
+	}
+
+	// Every field is required, left out fields are not kept.
+	w = serve(t, router, "PUT", "/api/v1/songs/3", `{"group":"Queen","name":"Bohemian Rhapsody"}`)
+	expectFields(t, w, "releaseDate", "text", "link")
+	w = serve(t, router, "PUT", "/api/v1/songs/3", `{"group":"Queen","name":"Bohemian Rhapsody","releaseDate":"","text":"","link":"","album":"A Night at the Opera"}`)
+	expectStatus(t, w, 400)
+
+	w = serve(t, router, "PUT", "/api/v1/songs/50", queenPut)
+	expectStatus(t, w, 404)
+	w = serve(t, router, "PUT", "/api/v1/songs/1", queenPut)
+	expectStatus(t, w, 409)
+}
+
+func TestPutSongCreates(t *testing.T) {
+	router := newRouter(routes.Services{PutCreates: true})
+	body := `{"group":"Nirvana","name":"Lithium","releaseDate":"1992","text":"","link":""}`
+
+	w := serve(t, router, "PUT", "/api/v1/songs/50", body, "If-None-Match", "*")
+	expectStatus(t, w, 201)
+	if got := w.Header().Get("Location"); got != "/api/v1/songs/50" {
+		t.Errorf("Location = %q, want /api/v1/songs/50", got)
+	}
+
+	// If-None-Match: * only creates.
+	w = serve(t, router, "PUT", "/api/v1/songs/50", body, "If-None-Match", "*")
+	expectStatus(t, w, 412)
+	w = serve(t, router, "PUT", "/api/v1/songs/50", body)
+	expectStatus(t, w, 200)
+
+	// Posted songs are numbered after the created id.
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":"Nirvana","name":"Breed","releaseDate":"","text":"","link":""}`)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", "/api/v1/songs/51", "")
+	expectStatus(t, w, 200)
+	if song := decode[musiclib.SongPaginated](t, w); song.Name != "Breed" {
+		t.Fatalf("song 51 = %+v, want Breed", song)
+	}
+}
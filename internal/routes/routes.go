@@ -6,9 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -18,147 +17,495 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/lynxbites/musiclib"
-	"github.com/lynxbites/musiclib/internal/db"
+	"github.com/lynxbites/musiclib/internal/auth"
+	"github.com/lynxbites/musiclib/internal/httpcache"
+	"github.com/lynxbites/musiclib/internal/problem"
+	"github.com/lynxbites/musiclib/internal/ratelimit"
 	_ "github.com/swaggo/http-swagger/example/go-chi/docs"
 )
 
 func init() {
 	log.SetReportCaller(true)
 }
-func NewRouter() *chi.Mux {
 
+// Services are the dependencies of the API handlers, optional ones may be nil.
+type Services struct {
+	Songs musiclib.SongStore
+	// Info fills in release date, lyrics and link missing from a posted song.
+	Info musiclib.SongInfoSource
+	// Groups enables the /api/v1/groups endpoints.
+	Groups musiclib.GroupStore
+	// Albums enables the /api/v1/albums endpoints.
+	Albums musiclib.AlbumStore
+	// Playlists enables the /api/v1/playlists endpoints.
+	Playlists musiclib.PlaylistStore
+	// SmartPlaylists enables the /api/v1/smart-playlists endpoints.
+	SmartPlaylists musiclib.SmartPlaylistStore
+	// Jobs moves the Info lookup of posted songs to background workers, without it
+	// the lookup runs inline.
+	Jobs musiclib.JobQueue
+	// Users and Auth enable the /api/v1/auth and /api/v1/users endpoints and make
+	// every mutation require a bearer token whose role has the permission for it,
+	// without them the API is open.
+	Users musiclib.UserStore
+	Auth  *auth.Authenticator
+	// APIKeys enables the /api/v1/api-keys endpoints and the X-API-Key header, it
+	// needs Users and Auth.
+	APIKeys musiclib.APIKeyStore
+	// RateLimit throttles clients and enforces API key quotas, it runs after
+	// authentication to tell clients apart.
+	RateLimit *ratelimit.Limiter
+	// PutCreates lets PUT /api/v1/songs/{songId} create a missing song under that id,
+	// without it PUT only replaces existing songs and answers 404.
+	PutCreates bool
+	// RequireIfMatch makes PUT, PATCH and DELETE of a song fail with 428 unless they
+	// send If-Match, without it they may skip the version check. It is off by default.
+	RequireIfMatch bool
+	// CacheControl sets the Cache-Control of song reads by route.
+	CacheControl httpcache.Config
+}
+
+type handler struct {
+	songs          musiclib.SongStore
+	groups         musiclib.GroupStore
+	albums         musiclib.AlbumStore
+	playlists      musiclib.PlaylistStore
+	smartPlaylists musiclib.SmartPlaylistStore
+	info           musiclib.SongInfoSource
+	jobs           musiclib.JobQueue
+	users          musiclib.UserStore
+	auth           *auth.Authenticator
+	apiKeys        musiclib.APIKeyStore
+	rateLimit      *ratelimit.Limiter
+	putCreates     bool
+	requireIfMatch bool
+	cache          httpcache.Config
+}
+
+// requestIdHeader sends the id set by middleware.RequestID back to the client, so it
+// can be quoted when reporting a problem.
+func requestIdHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// healthChecker is implemented by stores that can report backend availability.
+type healthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// NewRouter builds the API router on top of the given services.
+func NewRouter(services Services) *chi.Mux {
+
+	h := &handler{
+		songs:          services.Songs,
+		groups:         services.Groups,
+		albums:         services.Albums,
+		playlists:      services.Playlists,
+		smartPlaylists: services.SmartPlaylists,
+		info:           services.Info,
+		jobs:           services.Jobs,
+		users:          services.Users,
+		auth:           services.Auth,
+		apiKeys:        services.APIKeys,
+		rateLimit:      services.RateLimit,
+		putCreates:     services.PutCreates,
+		requireIfMatch: services.RequireIfMatch,
+		cache:          services.CacheControl,
+	}
+	if h.users == nil {
+		h.auth = nil
+	}
+	if h.auth == nil {
+		h.apiKeys = nil
+	}
 	router := chi.NewRouter()
+	router.Use(middleware.RequestID, requestIdHeader)
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Error(w, r, 404, "No route matches the request path")
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+			if router.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		problem.Error(w, r, 405, "The route does not support "+r.Method)
+	})
 
-	router.Route("/api/v1/songs", func(r chi.Router) {
+	router.Get("/api/v1/health", h.health)
+
+	router.Group(func(r chi.Router) {
 		r.Use(middleware.Logger)
 		r.Use(cors.Handler(cors.Options{
 			AllowedOrigins:   []string{"http://*"},
 			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match", "If-Modified-Since", auth.APIKeyHeader},
+			ExposedHeaders:   []string{"ETag", "Link", "X-Total-Count", "WWW-Authenticate", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-Quota-Limit", "X-Quota-Remaining", "X-Request-Id"},
 			AllowCredentials: false,
 			MaxAge:           360,
 		}))
-		r.Get("/", getSongList)
-		r.Post("/", addSong)
-		r.Get("/{songId}", getSong)
-		r.Patch("/{songId}", patchSong)
-		r.Delete("/{songId}", deleteSong)
+		r.Use(h.authenticate)
+		if h.rateLimit != nil {
+			r.Use(h.rateLimit.Middleware)
+		}
+
+		if h.auth != nil {
+			r.Route("/api/v1/auth", func(r chi.Router) {
+				r.Post("/register", h.register)
+				r.Post("/login", h.login)
+				r.Post("/refresh", h.refresh)
+				r.Post("/logout", h.logout)
+				r.With(auth.RequireUser).Get("/me", h.getMe)
+			})
+			r.Route("/api/v1/users", func(r chi.Router) {
+				r.Use(h.require(auth.PermissionManageUsers))
+				r.Get("/", h.getUserList)
+				r.Get("/{userId}", h.getUser)
+				r.Put("/{userId}/role", h.setUserRole)
+			})
+		}
+		if h.apiKeys != nil {
+			r.Route("/api/v1/api-keys", func(r chi.Router) {
+				r.Use(auth.RequireUser, h.require(auth.PermissionManageAPIKeys))
+				r.Get("/", h.getAPIKeyList)
+				r.Post("/", h.addAPIKey)
+				r.Get("/{apiKeyId}", h.getAPIKey)
+				r.Patch("/{apiKeyId}", h.patchAPIKey)
+				r.Post("/{apiKeyId}/rotate", h.rotateAPIKey)
+				r.Delete("/{apiKeyId}", h.deleteAPIKey)
+			})
+		}
+
+		r.Route("/api/v1/songs", func(r chi.Router) {
+			r.With(h.check(auth.PermissionReadSongs)).Get("/", h.getSongList)
+			r.With(h.require(auth.PermissionWriteSongs)).Post("/", h.addSong)
+			r.With(h.check(auth.PermissionReadSongs)).Get("/search", h.searchSongs)
+			r.With(h.check(auth.PermissionReadSongs)).Get("/{songId}", h.getSong)
+			r.With(h.require(auth.PermissionWriteSongs)).Put("/{songId}", h.putSong)
+			r.With(h.require(auth.PermissionWriteSongs)).Patch("/{songId}", h.patchSong)
+			r.With(h.require(auth.PermissionDeleteSongs)).Delete("/{songId}", h.deleteSong)
+		})
+		if h.groups != nil {
+			r.Route("/api/v1/groups", func(r chi.Router) {
+				r.Get("/", h.getGroupList)
+				r.With(h.require(auth.PermissionWriteLibrary)).Post("/", h.addGroup)
+				r.Get("/{groupId}", h.getGroup)
+				r.With(h.require(auth.PermissionWriteLibrary)).Patch("/{groupId}", h.patchGroup)
+				r.With(h.require(auth.PermissionDeleteLibrary)).Delete("/{groupId}", h.deleteGroup)
+				r.With(h.check(auth.PermissionReadSongs)).Get("/{groupId}/songs", h.getGroupSongs)
+			})
+		}
+		if h.albums != nil {
+			r.Route("/api/v1/albums", func(r chi.Router) {
+				r.Get("/", h.getAlbumList)
+				r.With(h.require(auth.PermissionWriteLibrary)).Post("/", h.addAlbum)
+				r.Get("/{albumId}", h.getAlbum)
+				r.With(h.require(auth.PermissionDeleteLibrary)).Delete("/{albumId}", h.deleteAlbum)
+				r.With(h.require(auth.PermissionWriteLibrary)).Post("/{albumId}/tracks", h.addAlbumTrack)
+				r.With(h.require(auth.PermissionWriteLibrary)).Put("/{albumId}/tracks", h.setAlbumTracks)
+				r.With(h.require(auth.PermissionWriteLibrary)).Delete("/{albumId}/tracks/{songId}", h.removeAlbumTrack)
+			})
+		}
+		if h.playlists != nil {
+			r.Route("/api/v1/playlists", func(r chi.Router) {
+				r.Get("/", h.getPlaylistList)
+				r.With(h.require(auth.PermissionWritePlaylists)).Post("/", h.addPlaylist)
+				r.Get("/{playlistId}", h.getPlaylist)
+				r.With(h.require(auth.PermissionWritePlaylists), h.ownPlaylist(auth.PermissionWriteLibrary)).Patch("/{playlistId}", h.patchPlaylist)
+				r.With(h.require(auth.PermissionWritePlaylists), h.ownPlaylist(auth.PermissionDeleteLibrary)).Delete("/{playlistId}", h.deletePlaylist)
+				r.With(h.require(auth.PermissionWritePlaylists), h.ownPlaylist(auth.PermissionWriteLibrary)).Post("/{playlistId}/entries", h.addPlaylistEntry)
+				r.With(h.require(auth.PermissionWritePlaylists), h.ownPlaylist(auth.PermissionWriteLibrary)).Patch("/{playlistId}/entries/{entryId}", h.movePlaylistEntry)
+				r.With(h.require(auth.PermissionWritePlaylists), h.ownPlaylist(auth.PermissionWriteLibrary)).Delete("/{playlistId}/entries/{entryId}", h.removePlaylistEntry)
+			})
+		}
+		if h.smartPlaylists != nil {
+			r.Route("/api/v1/smart-playlists", func(r chi.Router) {
+				r.Get("/", h.getSmartPlaylistList)
+				r.With(h.require(auth.PermissionWriteLibrary)).Post("/", h.addSmartPlaylist)
+				r.Get("/{smartPlaylistId}", h.getSmartPlaylist)
+				r.With(h.require(auth.PermissionWriteLibrary)).Patch("/{smartPlaylistId}", h.patchSmartPlaylist)
+				r.With(h.require(auth.PermissionDeleteLibrary)).Delete("/{smartPlaylistId}", h.deleteSmartPlaylist)
+				r.With(h.check(auth.PermissionReadSongs)).Get("/{smartPlaylistId}/songs", h.getSmartPlaylistSongs)
+			})
+		}
+		r.With(h.check(auth.PermissionReadSongs)).Get("/api/v1/suggest", h.suggest)
+		r.With(h.require(auth.PermissionWriteSongs)).Get("/api/v1/jobs/{jobId}", h.getJob)
 	})
 
 	return router
 }
 
+// Health godoc
+// @Summary      Health check
+// @Description  Checks that the API can reach the database.
+// @Tags         Health
+// @Success      200  "OK"
+// @Failure      503  {object}  problem.Problem  "Service Unavailable"
+// @Router       /v1/health [get]
+func (h *handler) health(w http.ResponseWriter, r *http.Request) {
+	checker, ok := h.songs.(healthChecker)
+	if !ok {
+		w.WriteHeader(200)
+		return
+	}
+	err := checker.Health(r.Context())
+	if err != nil {
+		log.Error("Health check failed: %v", err)
+		problem.Error(w, r, 503, "Database unavailable")
+		return
+	}
+	w.WriteHeader(200)
+}
+
 // GetSongList godoc
 // @Summary      Get songs
 // @Description  Gets list of songs from DB, with filters and pagination.
 // @Tags         Songs
-// @Param   filter      query     string     false  "Filter by id, group, name, date, text or link."
+// @Param   groupId      query     int     false  "Id of the group."
+// @Param   group      query     string     false  "Group name, ignoring case."
+// @Param   groupPrefix      query     string     false  "Group name prefix."
+// @Param   groupContains      query     string     false  "Case-insensitive substring of the group name."
+// @Param   groupFuzzy      query     string     false  "Group name with possible typos, matched by trigram similarity."
+// @Param   name      query     string     false  "Exact song name."
+// @Param   namePrefix      query     string     false  "Song name prefix."
+// @Param   nameContains      query     string     false  "Case-insensitive substring of the song name."
+// @Param   nameFuzzy      query     string     false  "Song name with possible typos, matched by trigram similarity."
+// @Param   releaseDateFrom      query     string     false  "Earliest release date, inclusive. Accepts the same formats as releaseDate, e.g. 1961, 1961-05 or 45 BCE."
+// @Param   releaseDateTo      query     string     false  "Latest release date, inclusive of its whole year or month."
+// @Param   linkHost      query     string     false  "Host of the link, subdomains match too."
+// @Param   textContains      query     string     false  "Case-insensitive substring of the lyrics."
+// @Param   sort      query     string     false  "Comma-separated sort keys out of id, group, name, date, text and link, prefix with - for descending order."
+// @Param   filter      query     string     false  "Deprecated, use sort. Sort by id, group, name, date, text or link."
 // @Param   page      query     int     false 	"Number of the page."
 // @Param   items      query     int     false 	"How many items to display per page."
+// @Param   cursor      query     string     false 	"Keyset pagination token, pass it empty for the first page and then the returned next_cursor. Responds with musiclib.SongCursorPage. Sorting by text or link is not supported with cursor."
+// @Param   envelope      query     bool     false 	"Wrap the page into musiclib.SongListPage with total count and paging info."
 // @Produce      json
 // @Success      200 {array} musiclib.Song "OK"
-// @Failure      400  "Bad Request"
-// @Failure      500  "Internal error"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Param   If-None-Match      header     string     false		"ETag of the cached page, answered with 304 when it is still current."
+// @Header       200 {integer} X-Total-Count "Number of songs matching the filters"
+// @Header       200 {string} ETag "Hash of the page"
+// @Header       200 {string} Cache-Control "Caching policy of the route, set by the server"
+// @Success      304 "Not Modified"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
 // @Router       /v1/songs [get]
-func getSongList(w http.ResponseWriter, r *http.Request) {
-	conn := db.New()
-	defer conn.Close(context.Background())
+func (h *handler) getSongList(w http.ResponseWriter, r *http.Request) {
+	filter, invalid := parseSongFilter(r.URL.Query())
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+	h.listSongs(w, r, filter)
+}
 
-	query, err := conn.Query(context.Background(), "select * from songs")
-	if err != nil {
-		log.Error("Encountered error when trying to get song list: %v", err)
-		http.Error(w, "Encountered Internal Server Error: "+err.Error(), 500)
+// listSongs writes the songs matching filter, sorted and paged by the query parameters.
+func (h *handler) listSongs(w http.ResponseWriter, r *http.Request, filter musiclib.SongFilter) {
+	paramFilter := r.URL.Query().Get("filter")
+	paramSort := r.URL.Query().Get("sort")
+	paramPage := r.URL.Query().Get("page")
+	paramCursor := r.URL.Query().Get("cursor")
+	cursorMode := r.URL.Query().Has("cursor")
+	envelope, _ := strconv.ParseBool(r.URL.Query().Get("envelope"))
+
+	var err error
+	page, items, invalid := parsePage(r, 2)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
 	}
 
-	var songs []musiclib.Song
-	for query.Next() {
-		var song musiclib.Song
-		err := query.Scan(&song.Id, &song.Group, &song.Name, &song.ReleaseDate, &song.Text, &song.Link)
-		if err != nil {
-			log.Error("Encountered error when scanning row: %v", err)
-			http.Error(w, "Encountered Internal Server Error: "+err.Error(), 500)
+	sorts, invalid := parseSort(paramSort)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+	if paramSort == "" && musiclib.IsSortField(paramFilter) {
+		sorts = []musiclib.SongSort{{Field: paramFilter}}
+	}
+
+	opts := musiclib.SongListOptions{
+		Filter: filter,
+		Sort:   sorts,
+		Limit:  items,
+		Offset: (items * page) - items,
+	}
+	if cursorMode {
+		if paramPage != "" {
+			log.Debug("400 Bad Request: cursor and page are mutually exclusive")
+			problem.Validation(w, r, problem.FieldError{Field: "page", Message: "cannot be used with cursor"})
 			return
 		}
-		songs = append(songs, song)
+		// The cursor holds the sort values of the last song, lyrics or links would make
+		// tokens too long for a URL.
+		for _, sort := range sorts {
+			if sort.Field == musiclib.SortByText || sort.Field == musiclib.SortByLink {
+				field := "sort"
+				if paramSort == "" {
+					field = "filter"
+				}
+				log.Debug("400 Bad Request: cursor does not support sort by " + sort.Field)
+				problem.Validation(w, r, problem.FieldError{Field: field, Message: "cannot sort by text or link with cursor"})
+				return
+			}
+		}
+		if paramCursor != "" {
+			opts.After, err = decodeCursor(paramCursor, sorts)
+			if err != nil {
+				log.Debug("400 Bad Request: cursor " + err.Error())
+				problem.Validation(w, r, problem.FieldError{Field: "cursor", Message: err.Error()})
+				return
+			}
+		}
+		// One extra song tells whether there is a next page.
+		opts.Limit = items + 1
+		opts.Offset = 0
 	}
 
-	paramFilter := r.URL.Query().Get("filter")
-	paramPage := r.URL.Query().Get("page")
-	paramItems := r.URL.Query().Get("items")
+	songs, err := h.songs.List(r.Context(), opts)
+	if err != nil {
+		log.Error("Encountered error when trying to get song list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if songs == nil {
+		songs = []musiclib.Song{}
+	}
 
-	if paramFilter != "" {
+	total, err := h.songs.Count(r.Context(), opts.Filter)
+	if err != nil {
+		log.Error("Encountered error when trying to count songs: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 
-		switch paramFilter {
-		case "id":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].Id < songs[j].Id
-			})
-		case "group":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].Group < songs[j].Group
-			})
-		case "name":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].Name < songs[j].Name
-			})
-		case "date":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].ReleaseDate < songs[j].ReleaseDate
-			})
-		case "text":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].Text < songs[j].Text
-			})
-		case "link":
-			sort.Slice(songs, func(i, j int) bool {
-				return songs[i].Link < songs[j].Link
-			})
+	if cursorMode {
+		cursorPage := musiclib.SongCursorPage{Items: songs}
+		links := []pageLink{{rel: "first", params: map[string]string{"cursor": ""}}}
+		if len(songs) > items {
+			cursorPage.Items = songs[:items]
+			cursorPage.NextCursor = encodeCursor(sorts, songs[items-1])
+			links = append(links, pageLink{rel: "next", params: map[string]string{"cursor": cursorPage.NextCursor}})
 		}
+		setLinkHeader(w, r, links)
+		h.writeSongs(w, r, cursorPage)
+		return
 	}
 
-	page := 1
-	items := 2
+	lastPage := max((total+items-1)/items, 1)
+	setLinkHeader(w, r, offsetLinks(page, lastPage))
 
-	if paramPage != "" {
-		page, err = strconv.Atoi(paramPage)
-		if err != nil {
-			page = 1
-		}
-		if page <= 0 {
-			log.Debug("400 Bad Request")
-			http.Error(w, "Bad Request", 400)
-			return
-		}
+	if envelope {
+		h.writeSongs(w, r, musiclib.SongListPage{
+			Items:    songs,
+			Total:    total,
+			Page:     page,
+			PageSize: items,
+			HasNext:  opts.Offset+len(songs) < total,
+		})
+		return
+	}
+	h.writeSongs(w, r, songs)
+}
 
+// SearchSongs godoc
+// @Summary      Search songs
+// @Description  Full-text search over lyrics, song and group names, best matches first. Snippets highlight hits with <mark></mark>, verses are indexes of matching verses as returned by Get song.
+// @Tags         Songs
+// @Param   q      query     string     true  "Search query in web search syntax: words, quoted phrases, or, and -excluded words."
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many results to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.SongSearchResult "OK"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/songs/search [get]
+func (h *handler) searchSongs(w http.ResponseWriter, r *http.Request) {
+	paramQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	if paramQuery == "" {
+		log.Debug("400 Bad Request: empty query")
+		problem.Validation(w, r, problem.FieldError{Field: "q", Message: "is required"})
+		return
 	}
 
-	if paramItems != "" {
-		items, err = strconv.Atoi(paramItems)
-		if err != nil {
-			items = 2
-		}
-		if items <= 0 {
-			log.Debug("400 Bad Request")
-			http.Error(w, "Bad Request", 400)
-			return
-		}
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	results, err := h.songs.Search(r.Context(), musiclib.SongSearchOptions{
+		Query:  paramQuery,
+		Limit:  items,
+		Offset: (items * page) - items,
+	})
+	if err != nil {
+		log.Error("Encountered error when trying to search songs: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if results == nil {
+		results = []musiclib.SongSearchResult{}
 	}
 
-	offset := (items * page) - items
-	limit := offset + items
+	encoder := json.NewEncoder(w)
+	encoder.Encode(results)
+	log.Debug("200 OK")
+}
+
+// Suggest godoc
+// @Summary      Autocomplete
+// @Description  Suggests distinct group names and songs for a typed prefix, tolerating typos. Prefix matches score 1, the rest are scored by trigram similarity.
+// @Tags         Songs
+// @Param   prefix      query     string     true  "What the user has typed so far."
+// @Param   limit      query     int     false 	"Maximum number of groups and of songs, 10 by default."
+// @Produce      json
+// @Success      200 {object} musiclib.Suggestions "OK"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/suggest [get]
+func (h *handler) suggest(w http.ResponseWriter, r *http.Request) {
+	paramPrefix := strings.TrimSpace(r.URL.Query().Get("prefix"))
+	paramLimit := r.URL.Query().Get("limit")
+
+	if paramPrefix == "" {
+		log.Debug("400 Bad Request: empty prefix")
+		problem.Validation(w, r, problem.FieldError{Field: "prefix", Message: "is required"})
+		return
+	}
 
-	if limit > len(songs) {
-		limit = len(songs)
+	var err error
+	limit := 10
+	if paramLimit != "" {
+		limit, err = strconv.Atoi(paramLimit)
+		if err != nil || limit <= 0 {
+			log.Debug("400 Bad Request")
+			problem.Validation(w, r, problem.FieldError{Field: "limit", Message: "must be a positive integer"})
+			return
+		}
 	}
 
-	if offset > len(songs) {
-		offset = len(songs)
+	suggestions, err := h.songs.Suggest(r.Context(), paramPrefix, limit)
+	if err != nil {
+		log.Error("Encountered error when trying to get suggestions: %v", err)
+		problem.Internal(w, r)
+		return
 	}
-	songs = songs[offset:limit]
 
 	encoder := json.NewEncoder(w)
-	encoder.Encode(songs)
+	encoder.Encode(suggestions)
 	log.Debug("200 OK")
 }
 
@@ -171,35 +518,58 @@ func getSongList(w http.ResponseWriter, r *http.Request) {
 // @Param   limit      query     int     false		"How many verses to display."
 // @Param   	 songId      path     int     true  "Id of the song."
 // @Success      200 {object} musiclib.SongPaginated "OK"
-// @Failure      400  "Bad Request"
-// @Failure      404  "Not Found"
-// @Failure      500  "Internal error"
+// @Header       200 {string} Link "first, prev, next and last verse page links"
+// @Header       200 {integer} X-Total-Count "Number of verses in the song"
+// @Param   If-None-Match      header     string     false		"ETag of the cached song, answered with 304 when it is still current."
+// @Param   If-Modified-Since      header     string     false		"Last-Modified of the cached song, used without If-None-Match."
+// @Header       200 {string} ETag "Version of the song, send it in If-Match to update or delete the song"
+// @Header       200 {string} Last-Modified "Time of the latest write of the song"
+// @Header       200 {string} Cache-Control "Caching policy of the route, set by the server"
+// @Success      304 "Not Modified"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
 // @Router       /v1/songs/{songId} [get]
-func getSong(w http.ResponseWriter, r *http.Request) {
-	paramId := chi.URLParam(r, "songId")
+func (h *handler) getSong(w http.ResponseWriter, r *http.Request) {
 	paramOffset := r.URL.Query().Get("offset")
 	paramLimit := r.URL.Query().Get("limit")
-	conn := db.New()
-	defer conn.Close(context.Background())
-	var song musiclib.Song
-
-	querySelect, err := conn.Query(context.Background(), "select * from songs where songId = "+paramId)
+	id, err := parseSongId(chi.URLParam(r, "songId"))
 	if err != nil {
-		log.Error("Encountered error when trying to get song list: %v", err)
-		http.Error(w, "Encountered Internal Server Error: "+err.Error(), 500)
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
 		return
 	}
-	for querySelect.Next() {
-		err := querySelect.Scan(&song.Id, &song.Group, &song.Name, &song.ReleaseDate, &song.Text, &song.Link)
-		if err != nil {
-			log.Error("Encountered error when trying to scan query rows: %v", err)
-			http.Error(w, "Encountered Internal Server Error: "+err.Error(), 500)
-			return
+
+	offset := 0
+	limit := 3
+	var invalid []problem.FieldError
+	if paramOffset != "" {
+		offset, err = strconv.Atoi(paramOffset)
+		if err != nil || offset < 0 {
+			invalid = append(invalid, problem.FieldError{Field: "offset", Message: "must be a non-negative integer"})
 		}
 	}
-	if song.Id == "" {
+	if paramLimit != "" {
+		limit, err = strconv.Atoi(paramLimit)
+		if err != nil || limit <= 0 {
+			invalid = append(invalid, problem.FieldError{Field: "limit", Message: "must be a positive integer"})
+		}
+	}
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	song, err := h.songs.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrNotFound) {
 		log.Debug("404 Not found")
-		http.Error(w, "404 Not found", 404)
+		problem.Error(w, r, 404, "Song not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get song: %v", err)
+		problem.Internal(w, r)
 		return
 	}
 
@@ -210,40 +580,20 @@ func getSong(w http.ResponseWriter, r *http.Request) {
 
 	songPaginated := musiclib.SongPaginated{
 		Id:          song.Id,
+		GroupId:     song.GroupId,
 		Group:       song.Group,
 		Name:        song.Name,
 		ReleaseDate: song.ReleaseDate,
 		Text:        textParsed,
 		Link:        song.Link,
+		Version:     song.Version,
+		UpdatedAt:   song.UpdatedAt,
 	}
 
-	offset := 0
-	limit := 3
-
-	if paramOffset != "" {
-		offset, err = strconv.Atoi(paramOffset)
-		if err != nil {
-			offset = 0
-		}
-		if offset < 0 {
-			log.Debug("400 Bad Request")
-			http.Error(w, "Bad Request", 400)
-			return
-		}
-
-	}
-
-	if paramLimit != "" {
-		limit, err = strconv.Atoi(paramLimit)
-		if err != nil {
-			limit = 3
-		}
-		if limit <= 0 {
-			log.Debug("400 Bad Request")
-			http.Error(w, "Bad Request", 400)
-			return
-		}
-	}
+	total := len(songPaginated.Text)
+	songPaginated.TotalVerses = total
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, verseLinks(offset, limit, total))
 
 	limit = offset + limit
 
@@ -255,6 +605,9 @@ func getSong(w http.ResponseWriter, r *http.Request) {
 	}
 	songPaginated.Text = songPaginated.Text[offset:limit]
 
+	if h.notModified(w, r, songETag(song.Version), song.UpdatedAt) {
+		return
+	}
 	encoder := json.NewEncoder(w)
 	encoder.Encode(songPaginated)
 	log.Debug("200 OK")
@@ -262,179 +615,456 @@ func getSong(w http.ResponseWriter, r *http.Request) {
 
 // AddSong godoc
 // @Summary      Post song
-// @Description  Post song to DB.
+// @Description  Post song to DB. Only group and name are required, the group is matched by name regardless of case and created when missing, missing releaseDate, text and link are looked up in the music info API. When the lookup is queued as a background job the response is 202 with the job in the body and its URL in the Location header. releaseDate accepts YYYY-MM-DD, YYYY-MM, YYYY, DD.MM.YYYY or month names, optionally followed by BCE, and is returned in the same precision. Group, name, releaseDate and link are trimmed, group and name must not be blank and are limited to 255 characters, text to 65536 and link to 2048, link must be an absolute http or https URL. All invalid fields are reported at once.
 // @Tags         Songs
 // @Accept       json
-// @Param 		 json body string true "Song JSON Object" SchemaExample({"group":"Author name", "name":"Song name", "releaseDate":"2024-12-12", "text":"Lyrics", "link":"Link"})
+// @Param 		 json body string true "Song JSON Object" SchemaExample({"group":"Author name", "name":"Song name", "releaseDate":"2024-12-12", "text":"Lyrics", "link":"https://example.com/song"})
 // @Produce      json
 // @Success      200  "OK"
-// @Failure      400  "Bad Request"
-// @Failure      409  "Conflict"
-// @Failure      500  "Internal error"
+// @Success      202  {object}  musiclib.Job
+// @Header       202  {string}  Location  "URL of the enrichment job"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /v1/songs [post]
-func addSong(w http.ResponseWriter, r *http.Request) {
-	conn := db.New()
-	defer conn.Close(context.Background())
-
+func (h *handler) addSong(w http.ResponseWriter, r *http.Request) {
 	var songPost musiclib.SongPost
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	err := decoder.Decode(&songPost)
 	if err != nil {
 		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
-		http.Error(w, "Invalid JSON data", 400)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
 		return
 	}
-	fmt.Printf("song: %+v\n", songPost)
-	if !isPostValid(songPost) {
-		log.Debug("400 Bad Request: Invalid JSON: %+v\n", err)
-		http.Error(w, "Invalid JSON data", 400)
+	songPost.Normalize()
+	var invalid musiclib.ValidationErrors
+	if err := songPost.Validate(); errors.As(err, &invalid) {
+		log.Debug("400 Bad Request: Invalid JSON: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
 		return
 	}
 	if decoder.More() {
 		log.Debug("400 Bad Request: Additional data")
-		http.Error(w, "Additional data", 400)
+		problem.Error(w, r, 400, "Invalid JSON: unexpected data after the song object")
 		return
 	}
 
-	var exists bool
-	queryRows := conn.QueryRow(context.Background(), "select exists(select 1 from songs where groupName=$1 and songName=$2)", *songPost.Group, *songPost.Name)
-	queryRows.Scan(&exists)
-	if exists {
+	queued := h.jobs != nil && h.info != nil && !isPostComplete(songPost)
+	var song musiclib.Song
+	if queued {
+		song = songFromPost(songPost)
+	} else {
+		song = h.enrichPost(r.Context(), songPost)
+	}
+
+	song, err = h.songs.Create(r.Context(), song)
+	if errors.Is(err, musiclib.ErrConflict) {
 		log.Debug("409 No content: Song already exists")
-		http.Error(w, "Song already exists", 409)
+		problem.Error(w, r, 409, "Song already exists")
 		return
 	}
-
-	_, err = conn.Exec(context.Background(), `insert into songs (groupName, songName, releaseDate, songText, songLink) values ($1,$2,$3,$4,$5)`, *songPost.Group, *songPost.Name, *songPost.ReleaseDate, *songPost.Text, *songPost.Link)
 	if err != nil {
 		log.Error("Encountered error when trying to insert song data: %v", err)
-		http.Error(w, "Encountered Internal Server Error: "+err.Error(), 500)
+		problem.Internal(w, r)
 		return
 	}
 
-	w.WriteHeader(200)
+	if !queued {
+		w.WriteHeader(200)
+		log.Debug("200 OK")
+		return
+	}
+
+	// The song is stored already, a failed enqueue only leaves it without details.
+	songId, _ := strconv.Atoi(song.Id)
+	job, err := h.jobs.Enqueue(r.Context(), musiclib.JobKindEnrichSong, songId)
+	if err != nil {
+		log.Warn("Could not enqueue song enrichment", "song", song.Id, "err", err)
+		w.WriteHeader(200)
+		log.Debug("200 OK")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/jobs/%d", job.Id))
+	w.WriteHeader(202)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(job)
+	log.Debug("202 Accepted")
+}
+
+// GetJob godoc
+// @Summary      Get job
+// @Description  Get status of a background job. Failed jobs are retried with exponential backoff and become dead when they run out of attempts or fail permanently, lastError holds the latest failure. Needs the permission to write songs, like posting the song that queued the job.
+// @Tags         Jobs
+// @Produce      json
+// @Param        jobId  path  int  true  "Id of a job"
+// @Success      200  {object}  musiclib.Job
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /v1/jobs/{jobId} [get]
+func (h *handler) getJob(w http.ResponseWriter, r *http.Request) {
+	if h.jobs == nil {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Job not found")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "jobId"), 10, 64)
+	if err != nil || id <= 0 {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "jobId", Message: "must be a positive integer"})
+		return
+	}
+
+	job, err := h.jobs.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrJobNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Job not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get job: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.Encode(job)
 	log.Debug("200 OK")
+}
+
+// songFromPost builds the song to store from the posted fields only, the post is validated.
+func songFromPost(post musiclib.SongPost) musiclib.Song {
+	song := musiclib.Song{
+		Group: *post.Group,
+		Name:  *post.Name,
+	}
+	if post.ReleaseDate != nil {
+		song.ReleaseDate, _ = musiclib.ParseReleaseDate(*post.ReleaseDate)
+	}
+	if post.Text != nil {
+		song.Text = *post.Text
+	}
+	if post.Link != nil {
+		song.Link = *post.Link
+	}
+	return song
+}
+
+// isPostComplete reports whether the post has every field the music info API provides.
+func isPostComplete(post musiclib.SongPost) bool {
+	return post.ReleaseDate != nil && post.Text != nil && post.Link != nil
+}
 
-	//Get /info request // Я так и не понял что от меня требуется во втором задании, извиняюсь за недопонимание :C
-	requestString := *songPost.Group + "&" + "name=" + *songPost.Name
-	request, err := http.Get("https://example.com/info?group=" + url.QueryEscape(requestString))
+// enrichPost builds the song to store, fields missing from the post are looked up in
+// the music info API. Lookup failures are logged and leave the fields empty.
+func (h *handler) enrichPost(ctx context.Context, post musiclib.SongPost) musiclib.Song {
+	song := songFromPost(post)
+	if h.info == nil || isPostComplete(post) {
+		return song
+	}
+	detail, err := h.info.SongInfo(ctx, song.Group, song.Name)
 	if err != nil {
-		log.Error("Encountered error when requesting /info: %v", err)
+		log.Warn("Could not get song info", "group", song.Group, "name", song.Name, "err", err)
+		return song
+	}
+	if invalid := detail.Sanitize(); len(invalid) > 0 {
+		log.Warn("Dropped invalid song info", "group", song.Group, "name", song.Name, "err", invalid)
+	}
+	if post.ReleaseDate == nil {
+		song.ReleaseDate = detail.ReleaseDate
+	}
+	if post.Text == nil {
+		song.Text = detail.Text
 	}
-	fmt.Printf("request status: %v\n", request.Status)
-	body, err := io.ReadAll(request.Body)
+	if post.Link == nil {
+		song.Link = detail.Link
+	}
+	return song
+}
+
+// PutSong godoc
+// @Summary      Replace song
+// @Description  Replace song specified by id with the whole document. Every field is required, empty releaseDate, text and link mean the song has none, and fields are validated like on post. Nothing is looked up in the music info API. A missing song is created under the id when the server allows it, otherwise the response is 404. Send the ETag of the song in If-Match, it is optional unless the server is configured to require it (SONGREQUIREIFMATCH=true, then a missing If-Match is 428), a song changed since then is not written and the response is 412 with the current song.
+// @Tags         Songs
+// @Accept       json
+// @Produce      json
+// @Param 		 json body string true "Song JSON Object" SchemaExample({"group":"Author name", "name":"Song name", "releaseDate":"2024-12-12", "text":"Lyrics", "link":"https://example.com/song"})
+// @Param   	 songId      path     int     true  "Id of a song to replace."
+// @Param        If-Match  header  string  false  "ETags of the song the change is based on, * for any version, weak tags never match"
+// @Param        If-None-Match  header  string  false  "* to only create the song"
+// @Success      200  {object}  musiclib.Song
+// @Success      201  {object}  musiclib.Song
+// @Header       201  {string}  Location  "URL of the song"
+// @Header       200,201  {string}  ETag  "Version of the song"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      412  {object}  musiclib.Song  "Precondition Failed, the body is the current song"
+// @Failure      428  {object}  problem.Problem  "Precondition Required"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /v1/songs/{songId} [put]
+func (h *handler) putSong(w http.ResponseWriter, r *http.Request) {
+	id, err := parseSongId(chi.URLParam(r, "songId"))
 	if err != nil {
-		log.Error("/info body read error: %v", err)
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
+		return
+	}
+
+	var songPut musiclib.SongPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&songPut)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if decoder.More() {
+		log.Debug("400 Bad Request: Additional data")
+		problem.Error(w, r, 400, "Invalid JSON: unexpected data after the song object")
+		return
+	}
+	songPut.Normalize()
+	var invalid musiclib.ValidationErrors
+	if err := songPut.ValidateReplace(); errors.As(err, &invalid) {
+		log.Debug("400 Bad Request: Invalid JSON: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	versions, ok := h.songVersions(w, r)
+	if !ok {
+		return
+	}
+
+	replacement := songFromPost(songPut)
+	replacement.Id = strconv.Itoa(id)
+	var song musiclib.Song
+	var created bool
+	err = writeVersions(versions, func(version int) error {
+		var err error
+		song, created, err = h.songs.Put(r.Context(), replacement, version, h.putCreates)
+		return err
+	})
+	if errors.Is(err, musiclib.ErrNotFound) {
+		log.Debug("404 Not Found: Song does not exist")
+		problem.Error(w, r, 404, "Song not found")
+		return
+	}
+	if errors.Is(err, musiclib.ErrVersionMismatch) {
+		h.writeStaleSong(w, r, id)
+		return
+	}
+	if errors.Is(err, musiclib.ErrConflict) {
+		log.Debug("409 Conflict: Song already exists")
+		problem.Error(w, r, 409, "Another song with the same group and name already exists")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to replace song: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", songETag(song.Version))
+	if created {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/songs/%d", id))
+		w.WriteHeader(201)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.Encode(song)
+	if created {
+		log.Debug("201 Created")
+	} else {
+		log.Debug("200 OK")
 	}
-	fmt.Printf("request body: %v\n", string(body))
 }
 
 // PatchSong godoc
 // @Summary      Patch song
-// @Description  Update song specified by id.
+// @Description  Update song specified by id. The Content-Type picks the patch format: application/json sets the fields present and keeps the rest, application/merge-patch+json (RFC 7396) also clears fields set to null, application/json-patch+json (RFC 6902) applies test, replace and remove operations on /group, /name, /releaseDate, /text and /link in order. A failed test operation is 409. The patched song is trimmed and validated like on post, all invalid fields are reported at once, and it is read, patched and stored in one transaction. Send the ETag of the song in If-Match, it is optional unless the server is configured to require it (SONGREQUIREIFMATCH=true, then a missing If-Match is 428), a song changed since then is not written and the response is 412 with the current song.
 // @Tags         Songs
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
 // @Produce      json
-// @Param 		 json body string true "Song JSON Object" SchemaExample({"group":"Patched", "name":"PatchedName", "releaseDate":"2023-12-12", "text":"PatchedText", "link":"PatchedLink"})
+// @Param 		 json body string true "Song JSON Object" SchemaExample({"group":"Patched", "name":"PatchedName", "releaseDate":"2023-12-12", "text":"PatchedText", "link":"https://example.com/patched"})
 // @Param   	 songId      path     int     true  "Id of a song to patch."
-// @Success      200  "OK"
-// @Failure      400  "Bad Request"
-// @Failure      404  "Not Found"
-// @Failure      500  "Internal error"
+// @Param        If-Match  header  string  false  "ETags of the song the change is based on, * for any version, weak tags never match"
+// @Success      200  {object}  musiclib.Song
+// @Header       200  {string}  ETag  "Version of the song"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      415  {object}  problem.Problem  "Unsupported Media Type"
+// @Failure      412  {object}  musiclib.Song  "Precondition Failed, the body is the current song"
+// @Failure      428  {object}  problem.Problem  "Precondition Required"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /v1/songs/{songId} [patch]
-func patchSong(w http.ResponseWriter, r *http.Request) {
-
-	conn := db.New()
-	defer conn.Close(context.Background())
-
-	paramId := chi.URLParam(r, "songId")
+func (h *handler) patchSong(w http.ResponseWriter, r *http.Request) {
 
-	var exists bool
-	queryRows := conn.QueryRow(context.Background(), "select exists(select 1 from songs where songId = $1)", paramId)
-	queryRows.Scan(&exists)
-	if !exists {
-		log.Debug("400 Bad Request: Song does not exist")
-		http.Error(w, "Song does not exist", 400)
+	id, err := parseSongId(chi.URLParam(r, "songId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
 		return
 	}
 
-	var patchRequest musiclib.SongPatch
-	var patchedObject musiclib.SongPatch
-	queryObj := conn.QueryRow(context.Background(), "select groupName, songName, releaseDate, songText, songLink from songs where songId = "+paramId)
-	err := queryObj.Scan(&patchedObject.Group, &patchedObject.Name, &patchedObject.ReleaseDate, &patchedObject.Text, &patchedObject.Link)
-	if err != nil {
-		log.Error("Error while scanning object: ", err)
-		http.Error(w, "Error while patching", 500)
+	mediaType := mediaTypeJSON
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = contentType
+		}
+	}
+	var decode func(io.Reader) (songEdit, []problem.FieldError, error)
+	switch mediaType {
+	case mediaTypeJSON:
+		decode = decodeSongPatch
+	case mediaTypeMergePatch:
+		decode = decodeMergePatch
+	case mediaTypeJSONPatch:
+		decode = decodeJSONPatch
+	default:
+		log.Debug("415 Unsupported Media Type")
+		w.Header().Set("Accept-Patch", strings.Join([]string{mediaTypeJSON, mediaTypeMergePatch, mediaTypeJSONPatch}, ", "))
+		problem.Error(w, r, 415, "Content-Type must be application/json, application/merge-patch+json or application/json-patch+json")
 		return
 	}
-	fmt.Printf("patchedObject: %+v\n", patchedObject)
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&patchRequest)
+	edit, errs, err := decode(r.Body)
 	if err != nil {
-		log.Error("Error while decoding body: ", err)
-		http.Error(w, "Error while patching", 500)
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if len(errs) > 0 {
+		log.Debug("400 Bad Request: Invalid patch: %+v\n", errs)
+		problem.Validation(w, r, errs...)
 		return
 	}
-	fmt.Printf("patchRequest: %+v\n", patchRequest)
 
-	if patchRequest.Group != "" {
-		patchedObject.Group = patchRequest.Group
+	versions, ok := h.songVersions(w, r)
+	if !ok {
+		return
+	}
+
+	var song musiclib.Song
+	err = writeVersions(versions, func(version int) error {
+		var err error
+		song, err = h.songs.Patch(r.Context(), id, version, func(song musiclib.Song) (musiclib.Song, error) {
+			doc := postFromSong(song)
+			if err := edit(&doc); err != nil {
+				return song, err
+			}
+			doc.Normalize()
+			if err := doc.ValidateReplace(); err != nil {
+				return song, err
+			}
+			return songFromPost(doc), nil
+		})
+		return err
+	})
+	var invalid musiclib.ValidationErrors
+	var testFailed *patchTestError
+	if errors.Is(err, musiclib.ErrNotFound) {
+		log.Debug("404 Not Found: Song does not exist")
+		problem.Error(w, r, 404, "Song not found")
+		return
 	}
-	if patchRequest.Name != "" {
-		patchedObject.Name = patchRequest.Name
+	if errors.Is(err, musiclib.ErrVersionMismatch) {
+		h.writeStaleSong(w, r, id)
+		return
 	}
-	if patchRequest.ReleaseDate != "" {
-		patchedObject.ReleaseDate = patchRequest.ReleaseDate
+	if errors.As(err, &invalid) {
+		log.Debug("400 Bad Request: Invalid JSON: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
 	}
-	if patchRequest.Text != "" {
-		patchedObject.Text = patchRequest.Text
+	if errors.As(err, &testFailed) {
+		log.Debug("409 Conflict: " + testFailed.Error())
+		problem.Error(w, r, 409, "JSON Patch "+testFailed.Error())
+		return
 	}
-	if patchRequest.Link != "" {
-		patchedObject.Link = patchRequest.Link
+	if errors.Is(err, musiclib.ErrConflict) {
+		log.Debug("409 Conflict: Song already exists")
+		problem.Error(w, r, 409, "Another song with the same group and name already exists")
+		return
 	}
-
-	fmt.Printf("after patch: %+v\n", patchedObject)
-
-	_, err = conn.Exec(context.Background(), `update songs set groupName = $1, songName = $2, releaseDate = $3, songText = $4, songLink = $5 where songId = $6`, patchedObject.Group, patchedObject.Name, patchedObject.ReleaseDate, patchedObject.Text, patchedObject.Link, paramId)
 	if err != nil {
-		log.Error("Error while updating patch object: ", err)
-		http.Error(w, "Error while patching", 500)
+		log.Error("Error while patching song: ", err)
+		problem.Internal(w, r)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", songETag(song.Version))
+	encoder := json.NewEncoder(w)
+	encoder.Encode(song)
 	log.Debug("200 OK")
-	w.WriteHeader(200)
 }
 
 // DeleteSong godoc
 // @Summary      Delete song
-// @Description  Delete song from DB.
+// @Description  Delete song from DB. Send the ETag of the song in If-Match, it is optional unless the server is configured to require it (SONGREQUIREIFMATCH=true, then a missing If-Match is 428), a song changed since then is not written and the response is 412 with the current song.
 // @Tags         Songs
 // @Produce      json
 // @Param   	 songId      path     int     true  "Id of a song to delete"
+// @Param        If-Match  header  string  false  "ETags of the song the change is based on, * for any version, weak tags never match"
 // @Success      200,204 "OK"
-// @Failure      400  "Bad Request"
-// @Failure      500  "Internal error"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      412  {object}  musiclib.Song  "Precondition Failed, the body is the current song"
+// @Failure      428  {object}  problem.Problem  "Precondition Required"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
 // @Router       /v1/songs/{songId} [delete]
-func deleteSong(w http.ResponseWriter, r *http.Request) {
+func (h *handler) deleteSong(w http.ResponseWriter, r *http.Request) {
 
-	paramId := chi.URLParam(r, "songId")
-	idInt, err := strconv.Atoi(paramId)
+	id, err := parseSongId(chi.URLParam(r, "songId"))
 	if err != nil {
 		log.Debug("400 Bad request")
-		http.Error(w, "Bad request", 400)
-	}
-	if idInt <= 0 {
-		log.Debug("400 Bad request")
-		http.Error(w, "Bad request", 400)
+		problem.Validation(w, r, problem.FieldError{Field: "songId", Message: "must be a positive integer"})
+		return
 	}
 
-	conn := db.New()
-	defer conn.Close(context.Background())
+	versions, ok := h.songVersions(w, r)
+	if !ok {
+		return
+	}
 
-	_, err = conn.Exec(context.Background(), "delete from songs where songId = $1", paramId)
-	if err != nil {
+	err = writeVersions(versions, func(version int) error {
+		return h.songs.Delete(r.Context(), id, version)
+	})
+	if errors.Is(err, musiclib.ErrVersionMismatch) {
+		h.writeStaleSong(w, r, id)
+		return
+	}
+	if err != nil && !errors.Is(err, musiclib.ErrNotFound) {
 		log.Error("Encountered error when trying to delete data: %v", err)
-		http.Error(w, "Error while deleting", 500)
+		problem.Internal(w, r)
 		return
 	}
 	log.Debug("204 No Content")
@@ -442,40 +1072,16 @@ func deleteSong(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func isRowExists(conn *db.DB, key string, value string) (bool, error) {
-	var exists bool
-
-	queryRow := conn.QueryRow(context.Background(), "select exists(select 1 from songs where $1=$2)", key, value)
-	err := queryRow.Scan(&exists)
+// parseSongId parses a songId path parameter, only positive integers are valid.
+func parseSongId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
 	if err != nil {
-		return false, errors.New("Encountered error when trying to check if row exists: " + err.Error())
-	}
-	return exists, nil
-}
-
-func isPostValid(patch musiclib.SongPost) bool {
-	if patch.Group == nil {
-		log.Print("Invalid Group")
-		return false
+		return 0, err
 	}
-	if patch.Name == nil {
-		log.Print("Invalid Name")
-		return false
+	if id <= 0 {
+		return 0, errors.New("song id must be positive")
 	}
-	if patch.ReleaseDate == nil {
-		log.Print("Invalid releaseDate")
-		return false
-	}
-	if patch.Text == nil {
-		log.Print("Invalid Text")
-		return false
-	}
-	if patch.Link == nil {
-		log.Print("Invalid Link")
-		return false
-	}
-
-	return true
+	return id, nil
 }
 
 func removeEmptyStrings(arr []string) []string {
@@ -0,0 +1,297 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetSmartPlaylistList godoc
+// @Summary      Get smart playlists
+// @Description  Gets list of smart playlists with their rules, ordered by name.
+// @Tags         Smart playlists
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.SmartPlaylist "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of smart playlists"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/smart-playlists [get]
+func (h *handler) getSmartPlaylistList(w http.ResponseWriter, r *http.Request) {
+
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	playlists, err := h.smartPlaylists.List(r.Context(), items, (page-1)*items)
+	if err != nil {
+		log.Error("Encountered error when trying to get smart playlist list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if playlists == nil {
+		playlists = []musiclib.SmartPlaylist{}
+	}
+
+	total, err := h.smartPlaylists.Count(r.Context())
+	if err != nil {
+		log.Error("Encountered error when trying to count smart playlists: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlists)
+	log.Debug("200 OK")
+}
+
+// GetSmartPlaylist godoc
+// @Summary      Get smart playlist
+// @Description  Get smart playlist by id with its rules.
+// @Tags         Smart playlists
+// @Produce      json
+// @Param   	 smartPlaylistId      path     int     true  "Id of a smart playlist."
+// @Success      200  {object}  musiclib.SmartPlaylist
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/smart-playlists/{smartPlaylistId} [get]
+func (h *handler) getSmartPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.loadSmartPlaylist(w, r)
+	if !ok {
+		return
+	}
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlist)
+	log.Debug("200 OK")
+}
+
+// GetSmartPlaylistSongs godoc
+// @Summary      Get smart playlist songs
+// @Description  Gets the songs matching the rules of the smart playlist, evaluated on every request. Sorting and pagination work as in Get songs, the song list filters are not applied.
+// @Tags         Smart playlists
+// @Param   	 smartPlaylistId      path     int     true  "Id of a smart playlist."
+// @Param   sort      query     string     false  "Comma-separated sort keys out of id, group, name, date, text and link, prefix with - for descending order."
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Param   cursor      query     string     false 	"Keyset pagination token, pass it empty for the first page and then the returned next_cursor."
+// @Param   envelope      query     bool     false 	"Wrap the page into musiclib.SongListPage with total count and paging info."
+// @Produce      json
+// @Success      200 {array} musiclib.Song "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of songs matching the rules"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Router       /v1/smart-playlists/{smartPlaylistId}/songs [get]
+func (h *handler) getSmartPlaylistSongs(w http.ResponseWriter, r *http.Request) {
+	playlist, ok := h.loadSmartPlaylist(w, r)
+	if !ok {
+		return
+	}
+	filter, err := playlist.Rules.Filter()
+	if err != nil {
+		log.Error("Stored smart playlist rules are invalid", "playlist", playlist.Id, "err", err)
+		problem.Internal(w, r)
+		return
+	}
+	h.listSongs(w, r, filter)
+}
+
+// AddSmartPlaylist godoc
+// @Summary      Post smart playlist
+// @Description  Create a playlist defined by rules, a song belongs to it when it matches every rule. Each rule is an object with field, op and either value or values:
+// @Description  group and name take is (exact), in (values, any of up to 100 names), prefix, contains (case-insensitive) and fuzzy (trigram similarity);
+// @Description  releaseDate takes from and to (inclusive, in releaseDate formats such as 1961, 1961-05 or 45 BCE) and between (values with two bounds, an empty bound is open);
+// @Description  text takes contains (case-insensitive substring of the lyrics); link takes host (subdomains match too).
+// @Description  At most 20 rules, a field and op combination may be used once. No rules match every song.
+// @Tags         Smart playlists
+// @Accept       json
+// @Param 		 json body musiclib.SmartPlaylistPost true "Smart playlist JSON Object"
+// @Produce      json
+// @Success      201  {object}  musiclib.SmartPlaylist
+// @Header       201  {string}  Location  "URL of the smart playlist"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/smart-playlists [post]
+func (h *handler) addSmartPlaylist(w http.ResponseWriter, r *http.Request) {
+	var playlistPost musiclib.SmartPlaylistPost
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&playlistPost)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if playlistPost.Name == nil || strings.TrimSpace(*playlistPost.Name) == "" {
+		log.Debug("400 Bad Request: Invalid Name")
+		problem.Validation(w, r, problem.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+	if _, err := playlistPost.Rules.Filter(); err != nil {
+		log.Debug("400 Bad Request: " + err.Error())
+		problem.Validation(w, r, problem.FieldError{Field: "rules", Message: err.Error()})
+		return
+	}
+
+	playlist, err := h.smartPlaylists.Create(r.Context(), musiclib.SmartPlaylist{
+		Name:  strings.TrimSpace(*playlistPost.Name),
+		Rules: playlistPost.Rules,
+	})
+	if err != nil {
+		log.Error("Encountered error when trying to insert smart playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/smart-playlists/%d", playlist.Id))
+	w.WriteHeader(201)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlist)
+	log.Debug("201 Created")
+}
+
+// PatchSmartPlaylist godoc
+// @Summary      Patch smart playlist
+// @Description  Rename a smart playlist or replace its rules, see Post smart playlist for the rule language.
+// @Tags         Smart playlists
+// @Accept       json
+// @Param 		 json body musiclib.SmartPlaylistPatch true "Smart playlist JSON Object"
+// @Param   	 smartPlaylistId      path     int     true  "Id of a smart playlist to patch."
+// @Produce      json
+// @Success      200  {object}  musiclib.SmartPlaylist
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/smart-playlists/{smartPlaylistId} [patch]
+func (h *handler) patchSmartPlaylist(w http.ResponseWriter, r *http.Request) {
+	var patchRequest musiclib.SmartPlaylistPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&patchRequest)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if _, err := patchRequest.Rules.Filter(); err != nil {
+		log.Debug("400 Bad Request: " + err.Error())
+		problem.Validation(w, r, problem.FieldError{Field: "rules", Message: err.Error()})
+		return
+	}
+
+	playlist, ok := h.loadSmartPlaylist(w, r)
+	if !ok {
+		return
+	}
+	if name := strings.TrimSpace(patchRequest.Name); name != "" {
+		playlist.Name = name
+	}
+	if patchRequest.Rules != nil {
+		playlist.Rules = patchRequest.Rules
+	}
+
+	err = h.smartPlaylists.Update(r.Context(), playlist)
+	if errors.Is(err, musiclib.ErrSmartPlaylistNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Smart playlist not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to update smart playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(playlist)
+	log.Debug("200 OK")
+}
+
+// DeleteSmartPlaylist godoc
+// @Summary      Delete smart playlist
+// @Description  Delete a smart playlist, its songs are kept.
+// @Tags         Smart playlists
+// @Param   	 smartPlaylistId      path     int     true  "Id of a smart playlist to delete"
+// @Success      204 "No Content"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/smart-playlists/{smartPlaylistId} [delete]
+func (h *handler) deleteSmartPlaylist(w http.ResponseWriter, r *http.Request) {
+	id, err := parseSmartPlaylistId(chi.URLParam(r, "smartPlaylistId"))
+	if err != nil {
+		log.Debug("400 Bad request")
+		problem.Validation(w, r, problem.FieldError{Field: "smartPlaylistId", Message: "must be a positive integer"})
+		return
+	}
+
+	err = h.smartPlaylists.Delete(r.Context(), id)
+	if err != nil && !errors.Is(err, musiclib.ErrSmartPlaylistNotFound) {
+		log.Error("Encountered error when trying to delete smart playlist: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	log.Debug("204 No Content")
+	w.WriteHeader(204)
+}
+
+// loadSmartPlaylist gets the smart playlist named by the path, on failure it writes
+// the error response and returns false.
+func (h *handler) loadSmartPlaylist(w http.ResponseWriter, r *http.Request) (musiclib.SmartPlaylist, bool) {
+	id, err := parseSmartPlaylistId(chi.URLParam(r, "smartPlaylistId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "smartPlaylistId", Message: "must be a positive integer"})
+		return musiclib.SmartPlaylist{}, false
+	}
+
+	playlist, err := h.smartPlaylists.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrSmartPlaylistNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "Smart playlist not found")
+		return playlist, false
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get smart playlist: %v", err)
+		problem.Internal(w, r)
+		return playlist, false
+	}
+	return playlist, true
+}
+
+// parseSmartPlaylistId parses a smartPlaylistId path parameter, only positive integers are valid.
+func parseSmartPlaylistId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("smart playlist id must be positive")
+	}
+	return id, nil
+}
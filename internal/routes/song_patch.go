@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// Media types accepted by PATCH /api/v1/songs/{songId}.
+const (
+	mediaTypeJSON       = "application/json"
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// songEdit changes the song document, the result is normalized and validated afterwards.
+type songEdit func(doc *musiclib.SongPost) error
+
+// patchTestError is returned by a JSON Patch whose test operation does not match the song.
+type patchTestError struct {
+	index int
+	path  string
+}
+
+func (e *patchTestError) Error() string {
+	return fmt.Sprintf("test operation %d failed, %s does not match", e.index, e.path)
+}
+
+// postFromSong returns the stored song as a document with every field set.
+func postFromSong(song musiclib.Song) musiclib.SongPost {
+	releaseDate := song.ReleaseDate.String()
+	return musiclib.SongPost{
+		Group:       &song.Group,
+		Name:        &song.Name,
+		ReleaseDate: &releaseDate,
+		Text:        &song.Text,
+		Link:        &song.Link,
+	}
+}
+
+// songField returns the document field with the JSON name, or nil for other names.
+func songField(doc *musiclib.SongPost, name string) **string {
+	switch name {
+	case "group":
+		return &doc.Group
+	case "name":
+		return &doc.Name
+	case "releaseDate":
+		return &doc.ReleaseDate
+	case "text":
+		return &doc.Text
+	case "link":
+		return &doc.Link
+	}
+	return nil
+}
+
+// patchString decodes a string or null value, null becomes "".
+func patchString(raw json.RawMessage) (string, bool) {
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false
+	}
+	if value == nil {
+		return "", true
+	}
+	return *value, true
+}
+
+// decodeSongPatch reads a plain JSON patch, fields left out or null keep their value
+// and fields that are not song fields are an error.
+func decodeSongPatch(body io.Reader) (songEdit, []problem.FieldError, error) {
+	var patch musiclib.SongPatch
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, nil, err
+	}
+	return func(doc *musiclib.SongPost) error {
+		for _, field := range []struct {
+			value *string
+			dst   **string
+		}{
+			{patch.Group, &doc.Group},
+			{patch.Name, &doc.Name},
+			{patch.ReleaseDate, &doc.ReleaseDate},
+			{patch.Text, &doc.Text},
+			{patch.Link, &doc.Link},
+		} {
+			if field.value != nil {
+				*field.dst = field.value
+			}
+		}
+		return nil
+	}, nil, nil
+}
+
+// decodeMergePatch reads an RFC 7396 merge patch, null clears a field.
+func decodeMergePatch(body io.Reader) (songEdit, []problem.FieldError, error) {
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&patch); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string)
+	var errs []problem.FieldError
+	for _, field := range musiclib.SongFieldRules {
+		raw, ok := patch[field.Field]
+		if !ok {
+			continue
+		}
+		value, ok := patchString(raw)
+		if !ok {
+			errs = append(errs, problem.FieldError{Field: field.Field, Message: "must be a string or null"})
+			continue
+		}
+		values[field.Field] = value
+	}
+	var unknown []string
+	for name := range patch {
+		if songField(&musiclib.SongPost{}, name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, problem.FieldError{Field: name, Message: "is not a song field"})
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	return func(doc *musiclib.SongPost) error {
+		for name, value := range values {
+			*songField(doc, name) = &value
+		}
+		return nil
+	}, nil, nil
+}
+
+// jsonPatchOp is a single RFC 6902 operation, only test, replace and remove are supported.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeJSONPatch reads an RFC 6902 JSON Patch. Operations apply in order, remove
+// clears a field and a failed test stops the patch with a patchTestError.
+func decodeJSONPatch(body io.Reader) (songEdit, []problem.FieldError, error) {
+	var ops []jsonPatchOp
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ops); err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]string, len(ops))
+	var errs []problem.FieldError
+	for i, op := range ops {
+		prefix := fmt.Sprintf("[%d].", i)
+		if op.Op != "test" && op.Op != "replace" && op.Op != "remove" {
+			errs = append(errs, problem.FieldError{Field: prefix + "op", Message: "must be test, replace or remove"})
+		}
+		if !strings.HasPrefix(op.Path, "/") || songField(&musiclib.SongPost{}, op.Path[1:]) == nil {
+			errs = append(errs, problem.FieldError{Field: prefix + "path", Message: "must be /group, /name, /releaseDate, /text or /link"})
+		}
+		if op.Op == "remove" {
+			continue
+		}
+		value, ok := patchString(op.Value)
+		if !ok {
+			errs = append(errs, problem.FieldError{Field: prefix + "value", Message: "must be a string or null"})
+		}
+		values[i] = value
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	return func(doc *musiclib.SongPost) error {
+		for i, op := range ops {
+			field := songField(doc, op.Path[1:])
+			switch op.Op {
+			case "test":
+				if *field == nil || **field != values[i] {
+					return &patchTestError{index: i, path: op.Path}
+				}
+			case "replace":
+				*field = &values[i]
+			case "remove":
+				*field = new(string)
+			}
+		}
+		return nil
+	}, nil, nil
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// patchDoc returns a document with every field set like a stored song.
+func patchDoc() musiclib.SongPost {
+	return postFromSong(musiclib.Song{Group: "Muse", Name: "Uprising", Text: "Paranoia is in bloom", Link: "https://example.com/muse"})
+}
+
+func errorFields(errs []problem.FieldError) []string {
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field
+	}
+	return fields
+}
+
+func TestDecodeSongPatch(t *testing.T) {
+	edit, errs, err := decodeSongPatch(strings.NewReader(`{"name":"Resistance","link":null}`))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("decodeSongPatch: %v %v", err, errs)
+	}
+	doc := patchDoc()
+	edit(&doc)
+	if *doc.Name != "Resistance" || *doc.Link != "https://example.com/muse" || *doc.Group != "Muse" {
+		t.Errorf("doc = %q %q %q, want the name changed and null kept", *doc.Group, *doc.Name, *doc.Link)
+	}
+
+	if _, _, err := decodeSongPatch(strings.NewReader(`{"name":"Resistance","album":"The Resistance"}`)); err == nil {
+		t.Error("unknown field decoded without an error")
+	}
+}
+
+func TestDecodeMergePatch(t *testing.T) {
+	edit, errs, err := decodeMergePatch(strings.NewReader(`{"name":"Resistance","link":null}`))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("decodeMergePatch: %v %v", err, errs)
+	}
+	doc := patchDoc()
+	edit(&doc)
+	if *doc.Name != "Resistance" || *doc.Link != "" || *doc.Text != "Paranoia is in bloom" {
+		t.Errorf("doc = %q %q %q, want the name changed and the link cleared", *doc.Name, *doc.Text, *doc.Link)
+	}
+
+	_, errs, err = decodeMergePatch(strings.NewReader(`{"text":3,"name":"Resistance","year":2009,"album":"The Resistance"}`))
+	if err != nil {
+		t.Fatalf("decodeMergePatch: %v", err)
+	}
+	if got, want := errorFields(errs), []string{"text", "album", "year"}; !slices.Equal(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+
+	if _, _, err := decodeMergePatch(strings.NewReader(`[]`)); err == nil {
+		t.Error("array decoded as a merge patch")
+	}
+}
+
+func TestDecodeJSONPatch(t *testing.T) {
+	edit, errs, err := decodeJSONPatch(strings.NewReader(`[
+		{"op":"test","path":"/name","value":"Uprising"},
+		{"op":"replace","path":"/name","value":"Resistance"},
+		{"op":"remove","path":"/link"}
+	]`))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("decodeJSONPatch: %v %v", err, errs)
+	}
+	doc := patchDoc()
+	if err := edit(&doc); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if *doc.Name != "Resistance" || *doc.Link != "" {
+		t.Errorf("doc = %q %q, want the name replaced and the link removed", *doc.Name, *doc.Link)
+	}
+
+	edit, _, _ = decodeJSONPatch(strings.NewReader(`[{"op":"replace","path":"/text","value":""},{"op":"test","path":"/group","value":"Queen"}]`))
+	doc = patchDoc()
+	var testFailed *patchTestError
+	if err := edit(&doc); !errors.As(err, &testFailed) || testFailed.index != 1 || testFailed.path != "/group" {
+		t.Errorf("edit = %v, want the test of /group at 1 to fail", err)
+	}
+
+	_, errs, err = decodeJSONPatch(strings.NewReader(`[{"op":"add","path":"/name","value":"x"},{"op":"replace","path":"/album","value":1},{"op":"remove","path":"name"}]`))
+	if err != nil {
+		t.Fatalf("decodeJSONPatch: %v", err)
+	}
+	if got, want := errorFields(errs), []string{"[0].op", "[1].path", "[1].value", "[2].path"}; !slices.Equal(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+
+	if _, _, err := decodeJSONPatch(strings.NewReader(`[{"op":"remove","path":"/link","from":"/text"}]`)); err == nil {
+		t.Error("unknown operation member decoded without an error")
+	}
+}
@@ -0,0 +1,259 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/memstore"
+	"github.com/lynxbites/musiclib/internal/problem"
+	"github.com/lynxbites/musiclib/internal/routes"
+)
+
+func seedSongs() []musiclib.Song {
+	return []musiclib.Song{
+		{Group: "Muse", Name: "Supermassive Black Hole", ReleaseDate: musiclib.ReleaseDate{Year: 2006, Month: 6, Day: 19, Precision: "day"}, Text: `Ooh baby\nI thought I was a fool`, Link: "https://example.com/muse"},
+		{Group: "Frank Sinatra", Name: "Blue Moon", ReleaseDate: musiclib.ReleaseDate{Year: 1961, Precision: "year"}, Text: "Blue moon"},
+		{Group: "Queen", Name: "Bohemian Rhapsody", ReleaseDate: musiclib.ReleaseDate{Year: 1975, Month: 10, Precision: "month"}},
+	}
+}
+
+// newRouter returns a router without authentication on top of a seeded memstore.
+func newRouter(services routes.Services) http.Handler {
+	if services.Songs == nil {
+		services.Songs = memstore.NewSongStore(seedSongs()...)
+	}
+	return routes.NewRouter(services)
+}
+
+// serve runs a request, headers are pairs of name and value.
+func serve(t *testing.T, router http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d, body: %s", w.Code, want, w.Body)
+	}
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return v
+}
+
+// expectFields checks that the response is a validation problem about exactly the fields.
+func expectFields(t *testing.T, w *httptest.ResponseRecorder, fields ...string) {
+	t.Helper()
+	expectStatus(t, w, 400)
+	p := decode[problem.Problem](t, w)
+	got := make([]string, len(p.Errors))
+	for i, e := range p.Errors {
+		got[i] = e.Field
+	}
+	if p.Type != problem.TypeValidation || !slices.Equal(got, fields) {
+		t.Errorf("problem = %+v, want a validation problem about %v", p, fields)
+	}
+}
+
+func TestListSongs(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	// Two songs a page by default.
+	w := serve(t, router, "GET", "/api/v1/songs/", "")
+	expectStatus(t, w, 200)
+	if songs := decode[[]musiclib.Song](t, w); len(songs) != 2 {
+		t.Fatalf("songs = %+v, want the first 2 songs", songs)
+	}
+
+	w = serve(t, router, "GET", "/api/v1/songs/?group=Queen", "")
+	expectStatus(t, w, 200)
+	if songs := decode[[]musiclib.Song](t, w); len(songs) != 1 || songs[0].Group != "Queen" {
+		t.Fatalf("songs = %+v, want the Queen song", songs)
+	}
+}
+
+func TestGetSong(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/1", "")
+	expectStatus(t, w, 200)
+	song := decode[musiclib.SongPaginated](t, w)
+	if song.Name != "Supermassive Black Hole" || song.TotalVerses != 2 {
+		t.Fatalf("song = %+v, want Supermassive Black Hole with 2 verses", song)
+	}
+
+	w = serve(t, router, "GET", "/api/v1/songs/99", "")
+	expectStatus(t, w, 404)
+	w = serve(t, router, "GET", "/api/v1/songs/abc", "")
+	expectStatus(t, w, 400)
+}
+
+func TestGetSongVerseLinks(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/1?limit=1", "")
+	expectStatus(t, w, 200)
+	if got := w.Header().Get("X-Total-Count"); got != "2" {
+		t.Errorf("X-Total-Count = %q, want 2", got)
+	}
+	wantLink := `</api/v1/songs/1?limit=1&offset=0>; rel="first", </api/v1/songs/1?limit=1&offset=1>; rel="next", </api/v1/songs/1?limit=1&offset=1>; rel="last"`
+	if got := w.Header().Get("Link"); got != wantLink {
+		t.Errorf("Link = %s, want %s", got, wantLink)
+	}
+}
+
+func TestGetSongInvalidVerses(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "GET", "/api/v1/songs/1?offset=abc&limit=x", "")
+	expectFields(t, w, "offset", "limit")
+	w = serve(t, router, "GET", "/api/v1/songs/1?offset=-1", "")
+	expectFields(t, w, "offset")
+	w = serve(t, router, "GET", "/api/v1/songs/1?limit=0", "")
+	expectFields(t, w, "limit")
+
+	w = serve(t, router, "GET", "/api/v1/songs/1?offset=1&limit=1", "")
+	expectStatus(t, w, 200)
+	if song := decode[musiclib.SongPaginated](t, w); len(song.Text) != 1 || song.Text[0] != "I thought I was a fool" {
+		t.Fatalf("verses = %q, want the second verse", song.Text)
+	}
+}
+
+func TestSuggestInvalidLimit(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	for _, limit := range []string{"abc", "0", "-3"} {
+		w := serve(t, router, "GET", "/api/v1/suggest?prefix=mu&limit="+limit, "")
+		expectFields(t, w, "limit")
+	}
+	w := serve(t, router, "GET", "/api/v1/suggest?prefix=mu&limit=1", "")
+	expectStatus(t, w, 200)
+}
+
+func TestPostSong(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "POST", "/api/v1/songs/", `{"group":"Nirvana","name":"Lithium","releaseDate":"1992","text":"","link":""}`)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", "/api/v1/songs/?group=Nirvana", "")
+	expectStatus(t, w, 200)
+	if songs := decode[[]musiclib.Song](t, w); len(songs) != 1 || songs[0].Name != "Lithium" {
+		t.Fatalf("songs = %+v, want the Nirvana song", songs)
+	}
+
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":"Nirvana","name":"Lithium"}`)
+	expectStatus(t, w, 409)
+}
+
+func TestPatchSong(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "PATCH", "/api/v1/songs/2", `{"text":"Blue moon, you saw me standing alone"}`)
+	expectStatus(t, w, 200)
+	song := decode[musiclib.Song](t, w)
+	if song.Text != "Blue moon, you saw me standing alone" || song.Name != "Blue Moon" {
+		t.Fatalf("song = %+v, want the patched text and the old name", song)
+	}
+
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"name":"Supermassive Black Hole","group":"Muse"}`)
+	expectStatus(t, w, 409)
+
+	w = serve(t, router, "PATCH", "/api/v1/songs/99", `{"text":"x"}`)
+	expectStatus(t, w, 404)
+}
+
+func TestDeleteSong(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "DELETE", "/api/v1/songs/1", "")
+	expectStatus(t, w, 204)
+	w = serve(t, router, "GET", "/api/v1/songs/1", "")
+	expectStatus(t, w, 404)
+
+	// Deleting is idempotent.
+	w = serve(t, router, "DELETE", "/api/v1/songs/1", "")
+	expectStatus(t, w, 204)
+}
+
+func TestSongNamesIgnoreCase(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "POST", "/api/v1/songs/", `{"group":"muse","name":"Supermassive Black Hole","releaseDate":"","text":"","link":""}`)
+	expectStatus(t, w, 409)
+	w = serve(t, router, "PATCH", "/api/v1/songs/2", `{"group":"MUSE","name":"Supermassive Black Hole"}`)
+	expectStatus(t, w, 409)
+	w = serve(t, router, "PUT", "/api/v1/songs/3", `{"group":"Muse","name":"supermassive black hole","releaseDate":"","text":"","link":""}`)
+	expectStatus(t, w, 409)
+
+	w = serve(t, router, "GET", "/api/v1/songs/?group=queen", "")
+	expectStatus(t, w, 200)
+	if songs := decode[[]musiclib.Song](t, w); len(songs) != 1 || songs[0].Group != "Queen" {
+		t.Fatalf("songs = %+v, want the Queen song", songs)
+	}
+}
+
+func TestSearchSongs(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"fool", []string{"Supermassive Black Hole"}},
+		{"sinatra", []string{"Blue Moon"}},
+		{"black hole", []string{"Supermassive Black Hole"}},
+		// Group and song words are matched separately, like the Postgres store does.
+		{"muse hole", []string{}},
+	}
+	for _, tt := range tests {
+		w := serve(t, router, "GET", "/api/v1/songs/search?q="+url.QueryEscape(tt.query), "")
+		expectStatus(t, w, 200)
+		results := decode[[]musiclib.SongSearchResult](t, w)
+		got := make([]string, len(results))
+		for i, result := range results {
+			got[i] = result.Name
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPostSongInvalid(t *testing.T) {
+	router := newRouter(routes.Services{})
+
+	w := serve(t, router, "POST", "/api/v1/songs/", `{"group":"","name":"Lithium","link":"ftp://example.com"}`)
+	expectFields(t, w, "group", "link")
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":"Nirvana","name":"Lithium","releaseDate":"someday"}`)
+	expectFields(t, w, "releaseDate")
+
+	// Surrounding whitespace is trimmed before the song is stored.
+	w = serve(t, router, "POST", "/api/v1/songs/", `{"group":" Nirvana ","name":"Lithium ","releaseDate":"","text":"","link":""}`)
+	expectStatus(t, w, 200)
+	w = serve(t, router, "GET", "/api/v1/songs/?group=Nirvana", "")
+	expectStatus(t, w, 200)
+	if songs := decode[[]musiclib.Song](t, w); len(songs) != 1 || songs[0].Group != "Nirvana" || songs[0].Name != "Lithium" {
+		t.Fatalf("songs = %+v, want the trimmed Nirvana song", songs)
+	}
+}
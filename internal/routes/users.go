@@ -0,0 +1,174 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-chi/chi/v5"
+	"github.com/lynxbites/musiclib"
+	"github.com/lynxbites/musiclib/internal/problem"
+)
+
+// GetUserList godoc
+// @Summary      Get users
+// @Description  Gets list of users ordered by id, with pagination. Admins only.
+// @Tags         Users
+// @Param   page      query     int     false 	"Number of the page."
+// @Param   items      query     int     false 	"How many items to display per page."
+// @Produce      json
+// @Success      200 {array} musiclib.User "OK"
+// @Header       200 {string} Link "first, prev, next and last page links"
+// @Header       200 {integer} X-Total-Count "Number of users"
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/users [get]
+func (h *handler) getUserList(w http.ResponseWriter, r *http.Request) {
+
+	page, items, invalid := parsePage(r, 10)
+	if len(invalid) > 0 {
+		log.Debug("400 Bad Request: Invalid query: %+v\n", invalid)
+		problem.Validation(w, r, invalid...)
+		return
+	}
+
+	users, err := h.users.List(r.Context(), items, (page-1)*items)
+	if err != nil {
+		log.Error("Encountered error when trying to get user list: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	if users == nil {
+		users = []musiclib.User{}
+	}
+
+	total, err := h.users.Count(r.Context())
+	if err != nil {
+		log.Error("Encountered error when trying to count users: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	setLinkHeader(w, r, offsetLinks(page, max((total+items-1)/items, 1)))
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(users)
+	log.Debug("200 OK")
+}
+
+// GetUser godoc
+// @Summary      Get user
+// @Description  Get user by id. Admins only.
+// @Tags         Users
+// @Produce      json
+// @Param   	 userId      path     int     true  "Id of a user."
+// @Success      200  {object}  musiclib.User
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/users/{userId} [get]
+func (h *handler) getUser(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserId(chi.URLParam(r, "userId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "userId", Message: "must be a positive integer"})
+		return
+	}
+
+	user, err := h.users.Get(r.Context(), id)
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "User not found")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to get user: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(user)
+	log.Debug("200 OK")
+}
+
+// SetUserRole godoc
+// @Summary      Set user role
+// @Description  Assign viewer, editor or admin to a user. Admins only. The new role applies to access tokens issued after the change, the last admin cannot be demoted.
+// @Tags         Users
+// @Accept       json
+// @Param 		 json body musiclib.RolePut true "Role JSON Object"
+// @Param   	 userId      path     int     true  "Id of a user."
+// @Produce      json
+// @Success      200  {object}  musiclib.User
+// @Failure      400  {object}  problem.Problem  "Bad Request"
+// @Failure      401  {object}  problem.Problem  "Unauthorized"
+// @Failure      403  {object}  problem.Problem  "Forbidden"
+// @Failure      404  {object}  problem.Problem  "Not Found"
+// @Failure      409  {object}  problem.Problem  "Conflict"
+// @Failure      500  {object}  problem.Problem  "Internal error"
+// @Security     BearerAuth
+// @Router       /v1/users/{userId}/role [put]
+func (h *handler) setUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUserId(chi.URLParam(r, "userId"))
+	if err != nil {
+		log.Debug("400 Bad Request")
+		problem.Validation(w, r, problem.FieldError{Field: "userId", Message: "must be a positive integer"})
+		return
+	}
+
+	var rolePut musiclib.RolePut
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&rolePut)
+	if err != nil {
+		log.Debug("400 Bad Request: Error while decoding JSON: %+v\n", err)
+		problem.Error(w, r, 400, "Invalid JSON: "+err.Error())
+		return
+	}
+	if !rolePut.Role.Valid() {
+		log.Debug("400 Bad Request: Invalid Role")
+		problem.Validation(w, r, problem.FieldError{Field: "role", Message: "must be viewer, editor or admin"})
+		return
+	}
+
+	user, err := h.users.SetRole(r.Context(), id, rolePut.Role)
+	if errors.Is(err, musiclib.ErrUserNotFound) {
+		log.Debug("404 Not Found")
+		problem.Error(w, r, 404, "User not found")
+		return
+	}
+	if errors.Is(err, musiclib.ErrLastAdmin) {
+		log.Debug("409 Conflict: Last admin")
+		problem.Error(w, r, 409, "Cannot demote the last admin")
+		return
+	}
+	if err != nil {
+		log.Error("Encountered error when trying to set user role: %v", err)
+		problem.Internal(w, r)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.Encode(user)
+	log.Debug("200 OK")
+}
+
+func parseUserId(param string) (int, error) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if id <= 0 {
+		return 0, errors.New("user id must be positive")
+	}
+	return id, nil
+}
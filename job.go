@@ -0,0 +1,52 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrJobNotFound is returned by job queues when the requested job does not exist.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobKindEnrichSong fills in release date, lyrics and link of a song from the music info API.
+const JobKindEnrichSong = "enrich_song"
+
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	// JobDead jobs ran out of attempts or failed permanently and will not be retried.
+	JobDead JobStatus = "dead"
+)
+
+type Job struct {
+	Id          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	SongId      int       `json:"songId"`
+	Status      JobStatus `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts"`
+	RunAt       time.Time `json:"runAt"`
+	LastError   string    `json:"lastError,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// JobQueue is a durable queue of background jobs.
+type JobQueue interface {
+	// Enqueue adds a job that may run right away.
+	Enqueue(ctx context.Context, kind string, songId int) (Job, error)
+	// Get returns the job with the given id or ErrJobNotFound.
+	Get(ctx context.Context, id int64) (Job, error)
+	// Claim takes the next due job and leases it for the given duration, jobs whose lease
+	// ran out are handed out again. It returns false when no job is due.
+	Claim(ctx context.Context, lease time.Duration) (Job, bool, error)
+	// Complete marks a claimed job as done.
+	Complete(ctx context.Context, id int64) error
+	// Fail records a failed attempt. The job is retried at retryAt unless it is out of
+	// attempts or permanent is set, then it becomes dead.
+	Fail(ctx context.Context, id int64, reason string, retryAt time.Time, permanent bool) error
+}
@@ -0,0 +1,79 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrPlaylistNotFound is returned by playlist stores when the requested playlist does not exist.
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	// ErrEntryNotFound is returned when the playlist has no entry with the given id.
+	ErrEntryNotFound = errors.New("playlist entry not found")
+)
+
+type Playlist struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	// OwnerId is the user who created the playlist, zero when nobody owns it.
+	OwnerId   int       `json:"ownerId,omitempty"`
+	SongCount int       `json:"songCount"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Entries are ordered by position, they are only filled in for a single playlist.
+	Entries []PlaylistEntry `json:"entries,omitzero"`
+}
+
+// PlaylistEntry is one occurrence of a song in a playlist, the same song may be added
+// several times. Positions count from 1 without gaps.
+type PlaylistEntry struct {
+	Id       int64     `json:"id"`
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"addedAt"`
+	Song     Song      `json:"song"`
+}
+
+type PlaylistPost struct {
+	Name *string `json:"name"`
+}
+
+type PlaylistPatch struct {
+	Name string `json:"name,omitempty"`
+}
+
+// PlaylistEntryPost adds a song at Position, zero position appends it.
+type PlaylistEntryPost struct {
+	SongId   int `json:"songId"`
+	Position int `json:"position,omitempty"`
+}
+
+type PlaylistEntryPatch struct {
+	Position int `json:"position"`
+}
+
+// PlaylistStore keeps playlists and their entries. Changes to the entries of one
+// playlist are serialized, so concurrent moves never leave gaps or duplicate positions.
+type PlaylistStore interface {
+	// List returns a page of playlists without entries ordered by name, zero limit means no limit.
+	List(ctx context.Context, limit, offset int) ([]Playlist, error)
+	// Count returns the number of playlists.
+	Count(ctx context.Context) (int, error)
+	// Get returns the playlist with its entries or ErrPlaylistNotFound.
+	Get(ctx context.Context, id int) (Playlist, error)
+	// Create stores a new empty playlist owned by ownerId, zero for none, and returns it
+	// with its id set.
+	Create(ctx context.Context, name string, ownerId int) (Playlist, error)
+	// Update renames playlist.Id or returns ErrPlaylistNotFound.
+	Update(ctx context.Context, playlist Playlist) error
+	// Delete removes the playlist and its entries or returns ErrPlaylistNotFound.
+	Delete(ctx context.Context, id int) error
+	// AddEntry inserts a song at position, moving the entries from there on down by one.
+	// Positions past the end and zero append. It returns ErrPlaylistNotFound or ErrNotFound.
+	AddEntry(ctx context.Context, playlistId, songId, position int) (PlaylistEntry, error)
+	// MoveEntry moves an entry to position, clamped to the playlist length. It returns
+	// ErrPlaylistNotFound or ErrEntryNotFound.
+	MoveEntry(ctx context.Context, playlistId int, entryId int64, position int) error
+	// RemoveEntry removes an entry and closes the gap, or returns ErrPlaylistNotFound or ErrEntryNotFound.
+	RemoveEntry(ctx context.Context, playlistId int, entryId int64) error
+}
@@ -0,0 +1,231 @@
+package musiclib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatePrecision tells which parts of a ReleaseDate are known.
+type DatePrecision string
+
+const (
+	PrecisionYear  DatePrecision = "year"
+	PrecisionMonth DatePrecision = "month"
+	PrecisionDay   DatePrecision = "day"
+)
+
+// ReleaseDate is a calendar date known to year, month or day precision, possibly before
+// the common era. The zero value is an unknown date. In JSON it is a string such as
+// "2011-08-11", "1961-01", "1961" or "45 BCE".
+type ReleaseDate struct {
+	// Year counts from 1 in both eras, 45 BCE is Year 45 with BCE set.
+	Year      int
+	Month     int
+	Day       int
+	BCE       bool
+	Precision DatePrecision
+}
+
+var (
+	eraSuffix    = regexp.MustCompile(`(?i)\s*(BCE|BC|B\.C\.|CE|AD|A\.D\.)$`)
+	isoDay       = regexp.MustCompile(`^(\d{1,4})[-/](\d{1,2})[-/](\d{1,2})$`)
+	dottedDay    = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{1,4})$`)
+	isoMonth     = regexp.MustCompile(`^(\d{1,4})[-/](\d{1,2})$`)
+	yearOnly     = regexp.MustCompile(`^(\d{1,4})$`)
+	namedFormats = []struct {
+		layout    string
+		precision DatePrecision
+	}{
+		{time.RFC3339, PrecisionDay},
+		{"January 2, 2006", PrecisionDay},
+		{"January 2 2006", PrecisionDay},
+		{"2 January 2006", PrecisionDay},
+		{"Jan 2, 2006", PrecisionDay},
+		{"Jan 2 2006", PrecisionDay},
+		{"2 Jan 2006", PrecisionDay},
+		{"January 2006", PrecisionMonth},
+		{"Jan 2006", PrecisionMonth},
+	}
+)
+
+// ParseReleaseDate parses ISO dates ("2011-08-11", "2011-08", "2011"), dotted dates
+// ("11.08.2011"), dates with month names ("August 11, 2011", "Aug 2011") and any of
+// those followed by an era ("45 BCE", "44 BC", "1066 AD"). An empty string is an unknown date.
+func ParseReleaseDate(s string) (ReleaseDate, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return ReleaseDate{}, nil
+	}
+
+	var d ReleaseDate
+	if era := eraSuffix.FindStringSubmatch(value); era != nil {
+		d.BCE = strings.HasPrefix(strings.ToUpper(era[1]), "B")
+		value = strings.TrimSpace(value[:len(value)-len(era[0])])
+	} else if upper := strings.ToUpper(value); strings.HasPrefix(upper, "AD ") {
+		value = strings.TrimSpace(value[3:])
+	}
+
+	switch {
+	case isoDay.MatchString(value):
+		m := isoDay.FindStringSubmatch(value)
+		d.Year, d.Month, d.Day, d.Precision = atoi(m[1]), atoi(m[2]), atoi(m[3]), PrecisionDay
+	case dottedDay.MatchString(value):
+		m := dottedDay.FindStringSubmatch(value)
+		d.Year, d.Month, d.Day, d.Precision = atoi(m[3]), atoi(m[2]), atoi(m[1]), PrecisionDay
+	case isoMonth.MatchString(value):
+		m := isoMonth.FindStringSubmatch(value)
+		d.Year, d.Month, d.Precision = atoi(m[1]), atoi(m[2]), PrecisionMonth
+	case yearOnly.MatchString(value):
+		d.Year, d.Precision = atoi(value), PrecisionYear
+	default:
+		parsed := false
+		for _, format := range namedFormats {
+			t, err := time.Parse(format.layout, value)
+			if err != nil {
+				continue
+			}
+			d.Year, d.Month, d.Precision = t.Year(), int(t.Month()), format.precision
+			if format.precision == PrecisionDay {
+				d.Day = t.Day()
+			}
+			parsed = true
+			break
+		}
+		if !parsed {
+			return ReleaseDate{}, fmt.Errorf("invalid release date %q", s)
+		}
+	}
+
+	if err := d.validate(); err != nil {
+		return ReleaseDate{}, fmt.Errorf("invalid release date %q: %w", s, err)
+	}
+	return d, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func (d ReleaseDate) validate() error {
+	if d.Year < 1 {
+		return fmt.Errorf("year must be at least 1")
+	}
+	if d.BCE && d.Year > 4713 {
+		return fmt.Errorf("years before 4713 BCE are not supported")
+	}
+	if d.Precision == PrecisionYear {
+		return nil
+	}
+	if d.Month < 1 || d.Month > 12 {
+		return fmt.Errorf("month must be between 1 and 12")
+	}
+	if d.Precision == PrecisionMonth {
+		return nil
+	}
+	monthStart := ReleaseDate{Year: d.Year, Month: d.Month, BCE: d.BCE, Precision: PrecisionMonth}
+	if d.Day < 1 || d.Day > monthStart.End().Day() {
+		return fmt.Errorf("day is out of range for the month")
+	}
+	return nil
+}
+
+// IsZero reports whether the date is unknown.
+func (d ReleaseDate) IsZero() bool {
+	return d.Precision == ""
+}
+
+// Start returns the first day covered by the date, years before the common era use
+// astronomical numbering (1 BCE is year 0).
+func (d ReleaseDate) Start() time.Time {
+	year := d.Year
+	if d.BCE {
+		year = 1 - d.Year
+	}
+	month, day := max(d.Month, 1), max(d.Day, 1)
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the last day covered by the date.
+func (d ReleaseDate) End() time.Time {
+	switch d.Precision {
+	case PrecisionYear:
+		return d.Start().AddDate(1, 0, -1)
+	case PrecisionMonth:
+		return d.Start().AddDate(0, 1, -1)
+	}
+	return d.Start()
+}
+
+// ReleaseDateFromTime builds a date of the given precision, t uses astronomical year numbering.
+func ReleaseDateFromTime(t time.Time, precision DatePrecision) ReleaseDate {
+	d := ReleaseDate{Year: t.Year(), Precision: precision}
+	if d.Year <= 0 {
+		d.Year, d.BCE = 1-d.Year, true
+	}
+	if precision != PrecisionYear {
+		d.Month = int(t.Month())
+	}
+	if precision == PrecisionDay {
+		d.Day = t.Day()
+	}
+	return d
+}
+
+// Compare orders dates chronologically by their first day, unknown dates come last.
+func (d ReleaseDate) Compare(other ReleaseDate) int {
+	switch {
+	case d.IsZero() && other.IsZero():
+		return 0
+	case d.IsZero():
+		return 1
+	case other.IsZero():
+		return -1
+	}
+	return d.Start().Compare(other.Start())
+}
+
+// String formats the date the way ParseReleaseDate reads it back.
+func (d ReleaseDate) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	var s string
+	yearFormat := "%04d"
+	if d.BCE {
+		yearFormat = "%d"
+	}
+	switch d.Precision {
+	case PrecisionYear:
+		s = fmt.Sprintf(yearFormat, d.Year)
+	case PrecisionMonth:
+		s = fmt.Sprintf(yearFormat+"-%02d", d.Year, d.Month)
+	default:
+		s = fmt.Sprintf(yearFormat+"-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+	if d.BCE {
+		s += " BCE"
+	}
+	return s
+}
+
+func (d ReleaseDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *ReleaseDate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("release date must be a string")
+	}
+	parsed, err := ParseReleaseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
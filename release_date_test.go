@@ -0,0 +1,124 @@
+package musiclib
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseReleaseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ReleaseDate
+	}{
+		{"", ReleaseDate{}},
+		{"  ", ReleaseDate{}},
+		{"2011-08-11", ReleaseDate{Year: 2011, Month: 8, Day: 11, Precision: PrecisionDay}},
+		{"2011/8/1", ReleaseDate{Year: 2011, Month: 8, Day: 1, Precision: PrecisionDay}},
+		{"11.08.2011", ReleaseDate{Year: 2011, Month: 8, Day: 11, Precision: PrecisionDay}},
+		{"2011-08", ReleaseDate{Year: 2011, Month: 8, Precision: PrecisionMonth}},
+		{"1961", ReleaseDate{Year: 1961, Precision: PrecisionYear}},
+		{"August 11, 2011", ReleaseDate{Year: 2011, Month: 8, Day: 11, Precision: PrecisionDay}},
+		{"11 Aug 2011", ReleaseDate{Year: 2011, Month: 8, Day: 11, Precision: PrecisionDay}},
+		{"Aug 2011", ReleaseDate{Year: 2011, Month: 8, Precision: PrecisionMonth}},
+		{"2011-08-11T10:00:00Z", ReleaseDate{Year: 2011, Month: 8, Day: 11, Precision: PrecisionDay}},
+		{"45 BCE", ReleaseDate{Year: 45, BCE: true, Precision: PrecisionYear}},
+		{"44 bc", ReleaseDate{Year: 44, BCE: true, Precision: PrecisionYear}},
+		{"0044-03-15 BCE", ReleaseDate{Year: 44, Month: 3, Day: 15, BCE: true, Precision: PrecisionDay}},
+		{"1066 AD", ReleaseDate{Year: 1066, Precision: PrecisionYear}},
+		{"AD 1066", ReleaseDate{Year: 1066, Precision: PrecisionYear}},
+		{"2024-02-29", ReleaseDate{Year: 2024, Month: 2, Day: 29, Precision: PrecisionDay}},
+	}
+	for _, tt := range tests {
+		got, err := ParseReleaseDate(tt.input)
+		if err != nil {
+			t.Errorf("ParseReleaseDate(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseReleaseDate(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseReleaseDateInvalid(t *testing.T) {
+	for _, input := range []string{
+		"soon",
+		"0",
+		"2011-13",
+		"2011-02-30",
+		"2023-02-29",
+		"5000 BCE",
+		"12345",
+		"2011-08-11-01",
+	} {
+		if got, err := ParseReleaseDate(input); err == nil {
+			t.Errorf("ParseReleaseDate(%q) = %+v, want an error", input, got)
+		}
+	}
+}
+
+func TestReleaseDateString(t *testing.T) {
+	for _, input := range []string{"", "0987-01-02", "1961-05", "1961", "45 BCE", "44-03-15 BCE"} {
+		d, err := ParseReleaseDate(input)
+		if err != nil {
+			t.Fatalf("ParseReleaseDate(%q): %v", input, err)
+		}
+		if got := d.String(); got != input {
+			t.Errorf("ParseReleaseDate(%q).String() = %q", input, got)
+		}
+	}
+}
+
+func TestReleaseDateRange(t *testing.T) {
+	tests := []struct {
+		input      string
+		start, end string
+	}{
+		{"1961", "1961-01-01", "1961-12-31"},
+		{"2024-02", "2024-02-01", "2024-02-29"},
+		{"2011-08-11", "2011-08-11", "2011-08-11"},
+		// 1 BCE is year 0 in astronomical numbering.
+		{"1 BCE", "0000-01-01", "0000-12-31"},
+	}
+	for _, tt := range tests {
+		d, err := ParseReleaseDate(tt.input)
+		if err != nil {
+			t.Fatalf("ParseReleaseDate(%q): %v", tt.input, err)
+		}
+		start, end := d.Start().Format("2006-01-02"), d.End().Format("2006-01-02")
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s covers %s to %s, want %s to %s", tt.input, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestReleaseDateCompare(t *testing.T) {
+	dates := []string{"45 BCE", "1961", "1961-05", "1961-05-02", ""}
+	for i := 1; i < len(dates); i++ {
+		a, _ := ParseReleaseDate(dates[i-1])
+		b, _ := ParseReleaseDate(dates[i])
+		if a.Compare(b) >= 0 {
+			t.Errorf("%q does not sort before %q", dates[i-1], dates[i])
+		}
+	}
+}
+
+func TestReleaseDateJSON(t *testing.T) {
+	var song struct {
+		ReleaseDate ReleaseDate `json:"releaseDate"`
+	}
+	if err := json.Unmarshal([]byte(`{"releaseDate":"Aug 2011"}`), &song); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"releaseDate":"2011-08"}` {
+		t.Errorf("JSON = %s, want the ISO form", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"releaseDate":1961}`), &song); err == nil {
+		t.Error("a number was accepted as a release date")
+	}
+}
@@ -0,0 +1,231 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSmartPlaylistNotFound is returned by smart playlist stores when the requested playlist does not exist.
+var ErrSmartPlaylistNotFound = errors.New("smart playlist not found")
+
+// Limits of a rule set, they keep the generated queries small.
+const (
+	MaxSongRules      = 20
+	MaxSongRuleValues = 100
+)
+
+// SongRule is one condition of a smart playlist. Ops that take a list, in and
+// between, read Values, all others read Value.
+//
+//	group, name   is, in, prefix, contains, fuzzy
+//	releaseDate   from, to, between
+//	text          contains
+//	link          host
+type SongRule struct {
+	Field  string   `json:"field" enums:"group,name,releaseDate,text,link" example:"group"`
+	Op     string   `json:"op" enums:"is,in,prefix,contains,fuzzy,from,to,between,host" example:"in"`
+	Value  string   `json:"value,omitempty" example:""`
+	Values []string `json:"values,omitempty" example:"Frank Sinatra,Ken Blast"`
+}
+
+// SongRules are the rules of a smart playlist, a song has to match all of them.
+type SongRules []SongRule
+
+// Filter validates the rules and turns them into the song filter they describe.
+// Each filter field may be set by one rule only, so e.g. two contains rules on text
+// are rejected instead of one silently replacing the other.
+func (rules SongRules) Filter() (SongFilter, error) {
+	var f SongFilter
+	if len(rules) > MaxSongRules {
+		return f, fmt.Errorf("at most %d rules are allowed", MaxSongRules)
+	}
+
+	set := make(map[string]bool)
+	for i, rule := range rules {
+		target, err := rule.apply(&f)
+		if err != nil {
+			return f, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		for _, t := range target {
+			if set[t] {
+				return f, fmt.Errorf("rule %d: %s %s conflicts with an earlier rule", i+1, rule.Field, rule.Op)
+			}
+			set[t] = true
+		}
+	}
+	return f, nil
+}
+
+// apply sets the filter fields of the rule and returns their names.
+func (rule SongRule) apply(f *SongFilter) ([]string, error) {
+	switch rule.Field {
+	case "group", "name":
+		return rule.applyText(f)
+	case "releaseDate":
+		return rule.applyReleaseDate(f)
+	case "text":
+		if rule.Op != "contains" {
+			break
+		}
+		value, err := rule.value()
+		f.TextContains = value
+		return []string{"text"}, err
+	case "link":
+		if rule.Op != "host" {
+			break
+		}
+		value, err := rule.value()
+		f.LinkHost = value
+		return []string{"link"}, err
+	default:
+		return nil, fmt.Errorf("unknown field %q", rule.Field)
+	}
+	return nil, fmt.Errorf("unknown op %q for field %s", rule.Op, rule.Field)
+}
+
+func (rule SongRule) applyText(f *SongFilter) ([]string, error) {
+	group := rule.Field == "group"
+	pick := func(groupField, nameField *string) *string {
+		if group {
+			return groupField
+		}
+		return nameField
+	}
+
+	var dst *string
+	switch rule.Op {
+	case "in":
+		values, err := rule.values(1, MaxSongRuleValues)
+		if group {
+			f.GroupIn = values
+		} else {
+			f.NameIn = values
+		}
+		return []string{rule.Field + " in"}, err
+	case "is":
+		dst = pick(&f.Group, &f.Name)
+	case "prefix":
+		dst = pick(&f.GroupPrefix, &f.NamePrefix)
+	case "contains":
+		dst = pick(&f.GroupContains, &f.NameContains)
+	case "fuzzy":
+		dst = pick(&f.GroupFuzzy, &f.NameFuzzy)
+	default:
+		return nil, fmt.Errorf("unknown op %q for field %s", rule.Op, rule.Field)
+	}
+	value, err := rule.value()
+	*dst = value
+	return []string{rule.Field + " " + rule.Op}, err
+}
+
+func (rule SongRule) applyReleaseDate(f *SongFilter) ([]string, error) {
+	var from, to string
+	switch rule.Op {
+	case "from":
+		value, err := rule.value()
+		if err != nil {
+			return nil, err
+		}
+		from = value
+	case "to":
+		value, err := rule.value()
+		if err != nil {
+			return nil, err
+		}
+		to = value
+	case "between":
+		values, err := rule.values(2, 2)
+		if err != nil {
+			return nil, err
+		}
+		from, to = values[0], values[1]
+	default:
+		return nil, fmt.Errorf("unknown op %q for field %s", rule.Op, rule.Field)
+	}
+
+	var targets []string
+	if from != "" {
+		d, err := ParseReleaseDate(from)
+		if err != nil {
+			return nil, err
+		}
+		f.ReleaseDateFrom = d
+		targets = append(targets, "releaseDate from")
+	}
+	if to != "" {
+		d, err := ParseReleaseDate(to)
+		if err != nil {
+			return nil, err
+		}
+		f.ReleaseDateTo = d
+		targets = append(targets, "releaseDate to")
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("releaseDate needs at least one bound")
+	}
+	return targets, nil
+}
+
+// value returns the non-blank Value of a single-value op.
+func (rule SongRule) value() (string, error) {
+	if rule.Values != nil {
+		return "", fmt.Errorf("op %s takes value, not values", rule.Op)
+	}
+	if strings.TrimSpace(rule.Value) == "" {
+		return "", fmt.Errorf("op %s needs a value", rule.Op)
+	}
+	return rule.Value, nil
+}
+
+// values returns Values of a list op, checking their number.
+func (rule SongRule) values(least, most int) ([]string, error) {
+	if rule.Value != "" {
+		return nil, fmt.Errorf("op %s takes values, not value", rule.Op)
+	}
+	if len(rule.Values) < least || len(rule.Values) > most {
+		if least == most {
+			return nil, fmt.Errorf("op %s needs %d values", rule.Op, least)
+		}
+		return nil, fmt.Errorf("op %s needs %d to %d values", rule.Op, least, most)
+	}
+	return rule.Values, nil
+}
+
+type SmartPlaylist struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Rules     SongRules `json:"rules"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type SmartPlaylistPost struct {
+	Name  *string   `json:"name"`
+	Rules SongRules `json:"rules"`
+}
+
+// SmartPlaylistPatch replaces the name and the whole rule set when they are given.
+type SmartPlaylistPatch struct {
+	Name  string    `json:"name,omitempty"`
+	Rules SongRules `json:"rules,omitempty"`
+}
+
+// SmartPlaylistStore keeps smart playlists, their songs are found with SongStore.List
+// using the filter of their rules.
+type SmartPlaylistStore interface {
+	// List returns a page of smart playlists ordered by name, zero limit means no limit.
+	List(ctx context.Context, limit, offset int) ([]SmartPlaylist, error)
+	// Count returns the number of smart playlists.
+	Count(ctx context.Context) (int, error)
+	// Get returns the smart playlist with the given id or ErrSmartPlaylistNotFound.
+	Get(ctx context.Context, id int) (SmartPlaylist, error)
+	// Create stores a new smart playlist and returns it with its id set.
+	Create(ctx context.Context, playlist SmartPlaylist) (SmartPlaylist, error)
+	// Update replaces name and rules of playlist.Id or returns ErrSmartPlaylistNotFound.
+	Update(ctx context.Context, playlist SmartPlaylist) error
+	// Delete removes the smart playlist or returns ErrSmartPlaylistNotFound.
+	Delete(ctx context.Context, id int) error
+}
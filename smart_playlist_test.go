@@ -0,0 +1,89 @@
+package musiclib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSongRulesFilter(t *testing.T) {
+	rules := SongRules{
+		{Field: "group", Op: "in", Values: []string{"Frank Sinatra", "Ken Blast"}},
+		{Field: "name", Op: "prefix", Value: "Blue"},
+		{Field: "group", Op: "fuzzy", Value: "sinatr"},
+		{Field: "releaseDate", Op: "between", Values: []string{"1950", "1969-12"}},
+		{Field: "text", Op: "contains", Value: "moon"},
+		{Field: "link", Op: "host", Value: "example.com"},
+	}
+	want := SongFilter{
+		GroupIn:         []string{"Frank Sinatra", "Ken Blast"},
+		NamePrefix:      "Blue",
+		GroupFuzzy:      "sinatr",
+		ReleaseDateFrom: ReleaseDate{Year: 1950, Precision: PrecisionYear},
+		ReleaseDateTo:   ReleaseDate{Year: 1969, Month: 12, Precision: PrecisionMonth},
+		TextContains:    "moon",
+		LinkHost:        "example.com",
+	}
+
+	got, err := rules.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSongRulesFilterBounds(t *testing.T) {
+	// from and to fill the same filter fields as between, one open bound is enough.
+	got, err := SongRules{{Field: "releaseDate", Op: "to", Value: "45 BCE"}, {Field: "name", Op: "is", Value: "Ave"}}.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SongFilter{Name: "Ave", ReleaseDateTo: ReleaseDate{Year: 45, BCE: true, Precision: PrecisionYear}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSongRulesFilterInvalid(t *testing.T) {
+	tooMany := make(SongRules, MaxSongRules+1)
+	for i := range tooMany {
+		tooMany[i] = SongRule{Field: "text", Op: "contains", Value: "a"}
+	}
+	tooManyValues := make([]string, MaxSongRuleValues+1)
+	for i := range tooManyValues {
+		tooManyValues[i] = "x"
+	}
+
+	tests := []struct {
+		name  string
+		rules SongRules
+		want  string
+	}{
+		{"too many rules", tooMany, "at most 20 rules"},
+		{"unknown field", SongRules{{Field: "rating", Op: "is", Value: "5"}}, `rule 1: unknown field "rating"`},
+		{"unknown op", SongRules{{Field: "text", Op: "is", Value: "a"}}, `rule 1: unknown op "is" for field text`},
+		{"unknown text op", SongRules{{Field: "name", Op: "between", Values: []string{"a", "b"}}}, `unknown op "between"`},
+		{"blank value", SongRules{{Field: "name", Op: "is", Value: "  "}}, "op is needs a value"},
+		{"values for value", SongRules{{Field: "link", Op: "host", Values: []string{"a"}}}, "op host takes value, not values"},
+		{"value for values", SongRules{{Field: "group", Op: "in", Value: "a"}}, "op in takes values, not value"},
+		{"too many values", SongRules{{Field: "group", Op: "in", Values: tooManyValues}}, "op in needs 1 to 100 values"},
+		{"one bound of between", SongRules{{Field: "releaseDate", Op: "between", Values: []string{"1961"}}}, "op between needs 2 values"},
+		{"bad date", SongRules{{Field: "releaseDate", Op: "from", Value: "soon"}}, `invalid release date "soon"`},
+		{"conflicting rules", SongRules{
+			{Field: "text", Op: "contains", Value: "a"},
+			{Field: "text", Op: "contains", Value: "b"},
+		}, "rule 2: text contains conflicts with an earlier rule"},
+		{"between after from", SongRules{
+			{Field: "releaseDate", Op: "from", Value: "1961"},
+			{Field: "releaseDate", Op: "between", Values: []string{"1950", "1960"}},
+		}, "rule 2: releaseDate between conflicts"},
+	}
+	for _, tt := range tests {
+		_, err := tt.rules.Filter()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -1,35 +1,232 @@
 package musiclib
 
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
 type Song struct {
-	Id          string `json:"id"`
-	Group       string `json:"group"`
-	Name        string `json:"name"`
-	ReleaseDate string `json:"releaseDate"`
-	Text        string `json:"text"`
-	Link        string `json:"link"`
+	Id          string      `json:"id"`
+	GroupId     int         `json:"groupId,omitempty"`
+	Group       string      `json:"group"`
+	Name        string      `json:"name"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2011-08-11"`
+	Text        string      `json:"text"`
+	Link        string      `json:"link"`
+	// Version changes with every write and is never reused, not even by another song,
+	// the ETag of the song is made from it.
+	Version int `json:"version" example:"1"`
+	// UpdatedAt is the time of the latest write, sent as Last-Modified.
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type SongPaginated struct {
-	Id          string   `json:"id"`
-	Group       string   `json:"group"`
-	Name        string   `json:"name"`
-	ReleaseDate string   `json:"releaseDate"`
-	Text        []string `json:"text"`
-	Link        string   `json:"link"`
+	Id          string      `json:"id"`
+	GroupId     int         `json:"groupId,omitempty"`
+	Group       string      `json:"group"`
+	Name        string      `json:"name"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2011-08-11"`
+	Text        []string    `json:"text"`
+	Link        string      `json:"link"`
+	TotalVerses int         `json:"totalVerses"`
+	Version     int         `json:"version" example:"1"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
 }
 
+// SongPost is a song to create, fields left out of the JSON are nil.
 type SongPost struct {
 	Group       *string `json:"group"`
 	Name        *string `json:"name"`
-	ReleaseDate *string `json:"releaseDate"`
+	ReleaseDate *string `json:"releaseDate" example:"2011-08-11"`
 	Text        *string `json:"text"`
 	Link        *string `json:"link"`
 }
 
+// SongPatch holds the fields to change, fields left out of the JSON are nil and keep their value.
 type SongPatch struct {
-	Group       string `json:"group,omitempty"`
-	Name        string `json:"name,omitempty"`
-	ReleaseDate string `json:"releaseDate,omitempty"`
-	Text        string `json:"text,omitempty"`
-	Link        string `json:"link,omitempty"`
+	Group       *string `json:"group,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	ReleaseDate *string `json:"releaseDate,omitempty" example:"2011-08-11"`
+	Text        *string `json:"text,omitempty"`
+	Link        *string `json:"link,omitempty"`
+}
+
+// Limits of song fields in characters.
+const (
+	MaxGroupLength = 255
+	MaxNameLength  = 255
+	MaxTextLength  = 65536
+	MaxLinkLength  = 2048
+)
+
+// FieldRules are the rules of one field, a missing field is checked as "".
+type FieldRules struct {
+	Field string
+	Rules []FieldRule
+}
+
+// SongFieldRules are the rules of each song field in the order violations are reported.
+var SongFieldRules = []FieldRules{
+	{"group", []FieldRule{Required, MaxLength(MaxGroupLength)}},
+	{"name", []FieldRule{Required, MaxLength(MaxNameLength)}},
+	{"releaseDate", []FieldRule{IsReleaseDate}},
+	{"text", []FieldRule{MaxLength(MaxTextLength)}},
+	{"link", []FieldRule{MaxLength(MaxLinkLength), HTTPURL}},
+}
+
+// missingFields tells validateSongFields how to check fields left out of the JSON.
+type missingFields int
+
+const (
+	// missingEmpty checks missing fields as "".
+	missingEmpty missingFields = iota
+	// missingSkipped leaves missing fields unchecked.
+	missingSkipped
+	// missingRejected reports missing fields as required.
+	missingRejected
+)
+
+// validateSongFields checks the values by JSON name against SongFieldRules.
+func validateSongFields(values map[string]*string, missing missingFields) error {
+	var errs ValidationErrors
+	for _, field := range SongFieldRules {
+		value := values[field.Field]
+		switch {
+		case value != nil:
+			errs.check(field.Field, *value, field.Rules...)
+		case missing == missingEmpty:
+			errs.check(field.Field, "", field.Rules...)
+		case missing == missingRejected:
+			errs = append(errs, FieldError{Field: field.Field, Message: "is required"})
+		}
+	}
+	return errs.err()
+}
+
+// trimField trims surrounding whitespace of a set field.
+func trimField(value *string) {
+	if value != nil {
+		*value = strings.TrimSpace(*value)
+	}
+}
+
+// Normalize trims group, name, releaseDate and link, text is kept as written.
+func (p *SongPost) Normalize() {
+	trimField(p.Group)
+	trimField(p.Name)
+	trimField(p.ReleaseDate)
+	trimField(p.Link)
+}
+
+// Validate checks the post against SongFieldRules and returns all violations as ValidationErrors.
+func (p SongPost) Validate() error {
+	return validateSongFields(p.fields(), missingEmpty)
+}
+
+// ValidateReplace checks the post as a whole document: every field has to be present,
+// empty releaseDate, text and link are allowed and mean the song has none.
+func (p SongPost) ValidateReplace() error {
+	return validateSongFields(p.fields(), missingRejected)
+}
+
+func (p SongPost) fields() map[string]*string {
+	return map[string]*string{
+		"group":       p.Group,
+		"name":        p.Name,
+		"releaseDate": p.ReleaseDate,
+		"text":        p.Text,
+		"link":        p.Link,
+	}
+}
+
+// Normalize trims group, name, releaseDate and link, text is kept as written.
+func (p *SongPatch) Normalize() {
+	trimField(p.Group)
+	trimField(p.Name)
+	trimField(p.ReleaseDate)
+	trimField(p.Link)
+}
+
+// Validate checks the set fields of the patch against SongFieldRules and returns all
+// violations as ValidationErrors.
+func (p SongPatch) Validate() error {
+	return validateSongFields(map[string]*string{
+		"group":       p.Group,
+		"name":        p.Name,
+		"releaseDate": p.ReleaseDate,
+		"text":        p.Text,
+		"link":        p.Link,
+	}, missingSkipped)
+}
+
+// Sanitize makes the details fit SongFieldRules before they are stored with a song,
+// they come from outside and are not checked by anyone else. Text is cut to
+// MaxTextLength, an invalid release date or link is dropped. The returned violations
+// are the ones that were fixed.
+func (d *SongDetail) Sanitize() ValidationErrors {
+	var errs ValidationErrors
+	switch {
+	case d.UnparsedReleaseDate != "":
+		errs.check("releaseDate", d.UnparsedReleaseDate, IsReleaseDate)
+		d.UnparsedReleaseDate = ""
+	case !d.ReleaseDate.IsZero():
+		errs.check("releaseDate", d.ReleaseDate.String(), IsReleaseDate)
+		if len(errs) > 0 {
+			d.ReleaseDate = ReleaseDate{}
+		}
+	}
+	if utf8.RuneCountInString(d.Text) > MaxTextLength {
+		errs = append(errs, FieldError{Field: "text", Message: "was cut to " + strconv.Itoa(MaxTextLength) + " characters"})
+		d.Text = string([]rune(d.Text)[:MaxTextLength])
+	}
+	d.Link = strings.TrimSpace(d.Link)
+	before := len(errs)
+	errs.check("link", d.Link, MaxLength(MaxLinkLength), HTTPURL)
+	if len(errs) > before {
+		d.Link = ""
+	}
+	return errs
+}
+
+type SongCursorPage struct {
+	Items      []Song `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type SongSearchResult struct {
+	Id          string      `json:"id"`
+	Group       string      `json:"group"`
+	Name        string      `json:"name"`
+	ReleaseDate ReleaseDate `json:"releaseDate" swaggertype:"string" example:"2011-08-11"`
+	Link        string      `json:"link"`
+	Rank        float64     `json:"rank"`
+	Snippet     string      `json:"snippet"`
+	Verses      []int       `json:"verses"`
+}
+
+type GroupSuggestion struct {
+	Group string  `json:"group"`
+	Score float64 `json:"score"`
+}
+
+type SongSuggestion struct {
+	Id    string  `json:"id"`
+	Group string  `json:"group"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+type Suggestions struct {
+	Groups []GroupSuggestion `json:"groups"`
+	Songs  []SongSuggestion  `json:"songs"`
+}
+
+type SongListPage struct {
+	Items    []Song `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+	HasNext  bool   `json:"hasNext"`
 }
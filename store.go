@@ -0,0 +1,181 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound is returned by stores when the requested song does not exist.
+	ErrNotFound = errors.New("song not found")
+	// ErrConflict is returned by stores when a song with the same group and name already exists.
+	ErrConflict = errors.New("song already exists")
+	// ErrVersionMismatch is returned by stores when the song is not at the version the caller expects.
+	ErrVersionMismatch = errors.New("song version does not match")
+)
+
+// Expected versions of Put, Patch and Delete besides a Song.Version: AnyVersion skips
+// the check and NoVersion only matches a song that does not exist yet.
+const (
+	AnyVersion = 0
+	NoVersion  = -1
+)
+
+// Sort keys accepted in SongSort.Field.
+const (
+	SortById    = "id"
+	SortByGroup = "group"
+	SortByName  = "name"
+	SortByDate  = "date"
+	SortByText  = "text"
+	SortByLink  = "link"
+)
+
+// IsSortField reports whether field is one of the SortBy* keys.
+func IsSortField(field string) bool {
+	switch field {
+	case SortById, SortByGroup, SortByName, SortByDate, SortByText, SortByLink:
+		return true
+	}
+	return false
+}
+
+// SongSort is a single sort key of a song list.
+type SongSort struct {
+	Field string
+	Desc  bool
+}
+
+// NormalizeSort returns the sort keys a store actually orders by: unknown keys are
+// dropped, keys after id are cut off and id is appended when missing, so the order is total.
+func NormalizeSort(sorts []SongSort) []SongSort {
+	var keys []SongSort
+	for _, sort := range sorts {
+		if !IsSortField(sort.Field) {
+			continue
+		}
+		keys = append(keys, sort)
+		if sort.Field == SortById {
+			return keys
+		}
+	}
+	return append(keys, SongSort{Field: SortById})
+}
+
+// SortValue returns the value of the song field named by a SortBy* key.
+func (s Song) SortValue(field string) string {
+	switch field {
+	case SortById:
+		return s.Id
+	case SortByGroup:
+		return s.Group
+	case SortByName:
+		return s.Name
+	case SortByDate:
+		return s.ReleaseDate.String()
+	case SortByText:
+		return s.Text
+	case SortByLink:
+		return s.Link
+	}
+	return ""
+}
+
+// SongFilter narrows a song list, empty fields are not applied and the rest are combined with AND.
+type SongFilter struct {
+	// GroupId matches songs of the group with this id.
+	GroupId int
+	// Group matches the group name ignoring case, as groups are told apart, GroupPrefix by prefix and
+	// GroupContains case-insensitively anywhere.
+	Group         string
+	GroupPrefix   string
+	GroupContains string
+	// GroupFuzzy matches group names similar to a misspelled word or name.
+	GroupFuzzy string
+	// GroupIn matches any of the listed group names ignoring case.
+	GroupIn []string
+	// Name, NamePrefix, NameContains, NameFuzzy and NameIn match the song name the same way,
+	// except that Name and NameIn are case-sensitive.
+	Name         string
+	NamePrefix   string
+	NameContains string
+	NameFuzzy    string
+	NameIn       []string
+	// ReleaseDateFrom and ReleaseDateTo are inclusive bounds of the release date, a bound
+	// covers its whole period, so ReleaseDateTo "1961" includes December 1961.
+	ReleaseDateFrom ReleaseDate
+	ReleaseDateTo   ReleaseDate
+	// LinkHost matches songs whose link points to this host or one of its subdomains.
+	LinkHost string
+	// TextContains matches lyrics containing the string, case-insensitively.
+	TextContains string
+}
+
+// SongListOptions selects, orders and pages the songs returned by SongStore.List.
+type SongListOptions struct {
+	Filter SongFilter
+	// Sort keys are applied in order, ties are always broken by id.
+	Sort []SongSort
+	// Limit is the maximum number of songs returned, zero means no limit.
+	Limit int
+	// Offset is the number of songs skipped before the page starts.
+	Offset int
+	// After holds the values of the normalized sort keys of the last song of the
+	// previous page, when set only songs ordered after it are returned.
+	After []string
+}
+
+// SongSearchOptions is a full-text query over lyrics, song and group names.
+type SongSearchOptions struct {
+	// Query uses web search syntax: words, "quoted phrases", or and -excluded words.
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SongDetail is what an external catalogue knows about a song.
+type SongDetail struct {
+	ReleaseDate ReleaseDate
+	// UnparsedReleaseDate is a release date the catalogue sent in a form ParseReleaseDate
+	// does not read, ReleaseDate is empty then. Sanitize reports and clears it.
+	UnparsedReleaseDate string
+	Text                string
+	Link                string
+}
+
+// SongInfoSource looks up details of a song by group and song name.
+type SongInfoSource interface {
+	SongInfo(ctx context.Context, group, name string) (SongDetail, error)
+}
+
+// SongStore is the storage the HTTP handlers work with.
+type SongStore interface {
+	// List returns a page of songs ordered according to opts.
+	List(ctx context.Context, opts SongListOptions) ([]Song, error)
+	// Count returns the number of songs matching filter.
+	Count(ctx context.Context, filter SongFilter) (int, error)
+	// Search returns songs matching a full-text query, best matches first. Snippets mark
+	// hits with <mark></mark> and Verses holds the matching verse indexes as split by getSong.
+	Search(ctx context.Context, opts SongSearchOptions) ([]SongSearchResult, error)
+	// Suggest returns distinct group names and songs similar to or starting with prefix, best matches first.
+	Suggest(ctx context.Context, prefix string, limit int) (Suggestions, error)
+	// Get returns the song with the given id or ErrNotFound.
+	Get(ctx context.Context, id int) (Song, error)
+	// Create stores a new song and returns it with its id set, or ErrConflict.
+	Create(ctx context.Context, song Song) (Song, error)
+	// Update replaces the stored fields of song.Id or returns ErrNotFound.
+	Update(ctx context.Context, song Song) error
+	// Put replaces the stored fields of song.Id, or creates the song under that id when
+	// create is set, and reports whether it was created. It returns ErrNotFound for a
+	// missing song without create, ErrVersionMismatch when the stored song is not at
+	// version and ErrConflict when another song has the same group and name.
+	Put(ctx context.Context, song Song, version int, create bool) (Song, bool, error)
+	// Patch replaces the song with the given id by the result of patch, which sees the
+	// stored song, and returns the new one. The song cannot change in between. Errors of
+	// patch are returned as they are, otherwise it returns ErrNotFound, ErrVersionMismatch
+	// or ErrConflict.
+	Patch(ctx context.Context, id int, version int, patch func(Song) (Song, error)) (Song, error)
+	// Delete removes the song with the given id from the library, its albums and playlists,
+	// or returns ErrNotFound or ErrVersionMismatch.
+	Delete(ctx context.Context, id int, version int) error
+}
@@ -0,0 +1,93 @@
+package musiclib
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUserNotFound is returned by user stores when the requested user does not exist.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserConflict is returned when the username is taken, regardless of case.
+	ErrUserConflict = errors.New("user already exists")
+	// ErrTokenInvalid is returned for refresh tokens that are unknown, expired or already used.
+	ErrTokenInvalid = errors.New("invalid token")
+	// ErrLastAdmin is returned when a role change would leave no admin.
+	ErrLastAdmin = errors.New("cannot demote the last admin")
+)
+
+// Role decides what a user may change, see auth.Allows for the permissions of each role.
+type Role string
+
+const (
+	// RoleViewer can only read, it is the role of newly registered users.
+	RoleViewer Role = "viewer"
+	// RoleEditor can add and edit songs and the rest of the library.
+	RoleEditor Role = "editor"
+	// RoleAdmin can also delete songs and assign roles.
+	RoleAdmin Role = "admin"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleViewer, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+type User struct {
+	Id           int       `json:"id"`
+	Username     string    `json:"username"`
+	Role         Role      `json:"role" enums:"viewer,editor,admin"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type Credentials struct {
+	Username string `json:"username" example:"listener"`
+	Password string `json:"password" example:"correct horse battery staple"`
+}
+
+// TokenPair is returned on login and refresh. The access token is a JWT sent as
+// "Authorization: Bearer <token>", the refresh token is opaque and works once.
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType" example:"Bearer"`
+	ExpiresIn    int    `json:"expiresIn" example:"900"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RolePut struct {
+	Role Role `json:"role" enums:"viewer,editor,admin"`
+}
+
+// UserStore keeps user accounts and their refresh tokens. Refresh tokens are only
+// stored as hashes.
+type UserStore interface {
+	// List returns users ordered by id.
+	List(ctx context.Context, limit, offset int) ([]User, error)
+	// Count returns the number of users.
+	Count(ctx context.Context) (int, error)
+	// Create stores a new user with user.Role and returns it with its id set, or ErrUserConflict.
+	Create(ctx context.Context, user User) (User, error)
+	// Get returns the user with the given id or ErrUserNotFound.
+	Get(ctx context.Context, id int) (User, error)
+	// GetByName returns the user with the given username, regardless of case, or ErrUserNotFound.
+	GetByName(ctx context.Context, username string) (User, error)
+	// SetRole changes the role of the user and returns the updated user, or ErrUserNotFound.
+	// Demoting the only admin fails with ErrLastAdmin.
+	SetRole(ctx context.Context, id int, role Role) (User, error)
+	// SaveRefreshToken stores the hash of a new refresh token of the user.
+	SaveRefreshToken(ctx context.Context, userId int, tokenHash []byte, expiresAt time.Time) error
+	// UseRefreshToken deletes the refresh token and returns its user id, or ErrTokenInvalid.
+	UseRefreshToken(ctx context.Context, tokenHash []byte) (int, error)
+	// RevokeRefreshToken deletes the refresh token, unknown tokens are ignored.
+	RevokeRefreshToken(ctx context.Context, tokenHash []byte) error
+}
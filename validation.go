@@ -0,0 +1,89 @@
+package musiclib
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError is a single invalid field, Field uses the JSON name.
+type FieldError struct {
+	Field   string `json:"field" example:"releaseDate"`
+	Message string `json:"message" example:"must be a date like 2006-01-02"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors lists every invalid field of a value, it is returned as an error
+// so callers can report all violations at once.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return "invalid fields: " + strings.Join(messages, "; ")
+}
+
+// FieldRule checks a single value and returns the violation message, or "" when the value is valid.
+type FieldRule func(value string) string
+
+// Required rejects empty and whitespace-only values.
+func Required(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "is required"
+	}
+	return ""
+}
+
+// MaxLength rejects values longer than n characters.
+func MaxLength(n int) FieldRule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > n {
+			return "must be at most " + strconv.Itoa(n) + " characters"
+		}
+		return ""
+	}
+}
+
+// HTTPURL rejects values that are not absolute http or https URLs, an empty value is allowed.
+func HTTPURL(value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an absolute http or https URL"
+	}
+	return ""
+}
+
+// IsReleaseDate rejects values ParseReleaseDate does not accept, an empty value is an unknown date.
+func IsReleaseDate(value string) string {
+	if _, err := ParseReleaseDate(value); err != nil {
+		return "must be a date like 2011-08-11, 2011-08, 2011 or 45 BCE"
+	}
+	return ""
+}
+
+// check applies the rules to the value and records the first violation of the field.
+func (e *ValidationErrors) check(field, value string, rules ...FieldRule) {
+	for _, rule := range rules {
+		if message := rule(value); message != "" {
+			*e = append(*e, FieldError{Field: field, Message: message})
+			return
+		}
+	}
+}
+
+// err returns nil for an empty list, a nil ValidationErrors would be a non-nil error.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
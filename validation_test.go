@@ -0,0 +1,156 @@
+package musiclib
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// invalidFields returns the fields of the ValidationErrors in err, nil for a nil err.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error %v is not ValidationErrors", err)
+	}
+	fields := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		fields[i] = fieldErr.Field
+	}
+	return fields
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestFieldRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  FieldRule
+		value string
+		valid bool
+	}{
+		{"required", Required, "Muse", true},
+		{"required", Required, "", false},
+		{"required", Required, " \t", false},
+		{"max length", MaxLength(3), "äöü", true},
+		{"max length", MaxLength(3), "abcd", false},
+		{"http url", HTTPURL, "", true},
+		{"http url", HTTPURL, "https://example.com/muse", true},
+		{"http url", HTTPURL, "ftp://example.com", false},
+		{"http url", HTTPURL, "example.com/muse", false},
+		{"http url", HTTPURL, "http://", false},
+		{"release date", IsReleaseDate, "", true},
+		{"release date", IsReleaseDate, "45 BCE", true},
+		{"release date", IsReleaseDate, "2011-13-01", false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule(tt.value) == ""; got != tt.valid {
+			t.Errorf("%s(%q) valid = %v, want %v", tt.name, tt.value, got, tt.valid)
+		}
+	}
+}
+
+func TestSongPostValidate(t *testing.T) {
+	tests := []struct {
+		post SongPost
+		want []string
+	}{
+		{SongPost{Group: ptr("Muse"), Name: ptr("Uprising")}, nil},
+		{SongPost{Group: ptr("Muse"), Name: ptr("Uprising"), ReleaseDate: ptr("2009-09-07"), Link: ptr("https://example.com")}, nil},
+		{SongPost{}, []string{"group", "name"}},
+		{SongPost{Group: ptr(" "), Name: ptr(strings.Repeat("a", MaxNameLength+1))}, []string{"group", "name"}},
+		{SongPost{Group: ptr("Muse"), Name: ptr("Uprising"), ReleaseDate: ptr("soon"), Link: ptr("ftp://example.com")}, []string{"releaseDate", "link"}},
+		{SongPost{Group: ptr("Muse"), Name: ptr("Uprising"), Text: ptr(strings.Repeat("a", MaxTextLength+1))}, []string{"text"}},
+	}
+	for _, tt := range tests {
+		if got := invalidFields(t, tt.post.Validate()); !slices.Equal(got, tt.want) {
+			t.Errorf("Validate(%+v) fields = %v, want %v", tt.post, got, tt.want)
+		}
+	}
+}
+
+func TestSongPostValidateReplace(t *testing.T) {
+	full := SongPost{Group: ptr("Muse"), Name: ptr("Uprising"), ReleaseDate: ptr(""), Text: ptr(""), Link: ptr("")}
+	if err := full.ValidateReplace(); err != nil {
+		t.Errorf("ValidateReplace(%+v): %v", full, err)
+	}
+
+	partial := SongPost{Group: ptr("Muse"), Name: ptr("Uprising"), Text: ptr("")}
+	err := partial.ValidateReplace()
+	if got, want := invalidFields(t, err), []string{"releaseDate", "link"}; !slices.Equal(got, want) {
+		t.Errorf("ValidateReplace(%+v) fields = %v, want %v", partial, got, want)
+	}
+	if want := "invalid fields: releaseDate is required; link is required"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestSongPatchValidate(t *testing.T) {
+	tests := []struct {
+		patch SongPatch
+		want  []string
+	}{
+		{SongPatch{}, nil},
+		{SongPatch{Text: ptr("")}, nil},
+		{SongPatch{Link: ptr("")}, nil},
+		{SongPatch{Group: ptr(""), ReleaseDate: ptr("2011-02-30")}, []string{"group", "releaseDate"}},
+		{SongPatch{Name: ptr(""), Link: ptr("mailto:muse@example.com")}, []string{"name", "link"}},
+	}
+	for _, tt := range tests {
+		if got := invalidFields(t, tt.patch.Validate()); !slices.Equal(got, tt.want) {
+			t.Errorf("Validate(%+v) fields = %v, want %v", tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	post := SongPost{Group: ptr(" Muse "), Name: ptr("\tUprising\n"), ReleaseDate: ptr(" 2009 "), Text: ptr(" They will not force us\n"), Link: ptr(" https://example.com ")}
+	post.Normalize()
+	if *post.Group != "Muse" || *post.Name != "Uprising" || *post.ReleaseDate != "2009" || *post.Link != "https://example.com" {
+		t.Errorf("post = %q %q %q %q, want the fields trimmed", *post.Group, *post.Name, *post.ReleaseDate, *post.Link)
+	}
+	if *post.Text != " They will not force us\n" {
+		t.Errorf("text = %q, want it kept as written", *post.Text)
+	}
+
+	patch := SongPatch{Name: ptr(" Uprising ")}
+	patch.Normalize()
+	if *patch.Name != "Uprising" || patch.Group != nil {
+		t.Errorf("patch = %+v, want the name trimmed and the group unset", patch)
+	}
+}
+
+func TestSongDetailSanitize(t *testing.T) {
+	detail := SongDetail{
+		ReleaseDate: ReleaseDate{Year: 2009, Month: 9, Day: 7, Precision: PrecisionDay},
+		Text:        strings.Repeat("ё", MaxTextLength+10),
+		Link:        "ftp://example.com",
+	}
+	errs := detail.Sanitize()
+	if got, want := invalidFields(t, errs.err()), []string{"text", "link"}; !slices.Equal(got, want) {
+		t.Errorf("fixed fields = %v, want %v", got, want)
+	}
+	if detail.Text != strings.Repeat("ё", MaxTextLength) || detail.Link != "" || detail.ReleaseDate.Year != 2009 {
+		t.Errorf("detail = %d characters, link %q, date %v, want the text cut and the link dropped", len([]rune(detail.Text)), detail.Link, detail.ReleaseDate)
+	}
+
+	detail = SongDetail{UnparsedReleaseDate: "sometime in 2009", Link: " https://example.com "}
+	errs = detail.Sanitize()
+	if got, want := invalidFields(t, errs.err()), []string{"releaseDate"}; !slices.Equal(got, want) {
+		t.Errorf("fixed fields = %v, want %v", got, want)
+	}
+	if detail.UnparsedReleaseDate != "" || !detail.ReleaseDate.IsZero() || detail.Link != "https://example.com" {
+		t.Errorf("detail = %+v, want the date cleared and the link trimmed", detail)
+	}
+
+	detail = SongDetail{Text: "Paranoia is in bloom"}
+	if errs := detail.Sanitize(); len(errs) != 0 {
+		t.Errorf("Sanitize of valid details = %v, want nothing fixed", errs)
+	}
+}